@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// @schema annotations in the comments of values files
+
+// annotationMarker opens and closes an annotation block in the head comment of a key:
+//
+//	# @schema
+//	# type: [string, "null"]
+//	# @schema
+//	tag: null
+const annotationMarker = "@schema"
+
+// annotationBlock returns the YAML between the @schema markers of a head comment.
+// found reports whether the comment has a block at all.
+func annotationBlock(comment string) (block string, found bool, err error) {
+	var lines []string
+	open := false
+	for _, line := range strings.Split(comment, "\n") {
+		text := strings.TrimPrefix(strings.TrimSpace(line), "#")
+		if strings.TrimSpace(text) == annotationMarker {
+			if open {
+				return strings.Join(lines, "\n"), true, nil
+			}
+			open = true
+			continue
+		}
+		if open {
+			// Strip the single space following "#", keeping the indentation of the YAML
+			lines = append(lines, strings.TrimPrefix(text, " "))
+		}
+	}
+	if open {
+		return "", true, fmt.Errorf("unterminated %s block", annotationMarker)
+	}
+	return "", false, nil
+}
+
+// stripAnnotations removes the @schema block from a head comment
+func stripAnnotations(comment string) string {
+	if !strings.Contains(comment, annotationMarker) {
+		return comment
+	}
+	var kept []string
+	open := false
+	for _, line := range strings.Split(comment, "\n") {
+		if strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#")) == annotationMarker {
+			open = !open
+			continue
+		}
+		if !open {
+			kept = append(kept, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// schemaAnnotations returns the JSON Schema fragments of the @schema blocks in the head
+// comments of the named values file, keyed by dotted values path as in a schema overlay.
+// Keywords without a value, as in the placeholders written by valet init, are skipped.
+// Comments are not validated otherwise, so a malformed block is reported as a warning and
+// skipped rather than failing the generation.
+func schemaAnnotations(name string, layout *valuesLayout) map[string]any {
+	paths := make([]string, 0, len(layout.comments))
+	for p := range layout.comments {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	annotations := make(map[string]any)
+	for _, p := range paths {
+		block, found, err := annotationBlock(layout.comments[p])
+		if err != nil {
+			fmt.Fprintf(stderr, "Warning: %s: %s: %v; skipping it\n", name, p, err)
+			continue
+		}
+		if !found {
+			continue
+		}
+		var fragment map[string]any
+		if err := yaml.Unmarshal([]byte(block), &fragment); err != nil {
+			fmt.Fprintf(stderr, "Warning: %s: %s: invalid %s block: %v; skipping it\n", name, p, annotationMarker, err)
+			continue
+		}
+		for k, v := range fragment {
+			if v == nil {
+				delete(fragment, k)
+			}
+		}
+		if len(fragment) > 0 {
+			annotations[p] = fragment
+		}
+	}
+	return annotations
+}
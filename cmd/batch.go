@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mkm29/valet/internal/config"
+	"github.com/mkm29/valet/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recursive (batch) mode for the generate subcommand
+
+// ChartResult is the outcome of generating the schema of one chart in recursive mode
+type ChartResult struct {
+	// Dir is the chart directory
+	Dir string
+	// Output is the file that was written
+	Output string
+	// Properties is the number of properties in the generated schema
+	Properties int
+	// Cached reports whether the generation was skipped because the inputs are unchanged
+	Cached bool
+	// Duration is how long the generation took
+	Duration time.Duration
+	// Err is the error that made the generation fail, if any
+	Err error
+}
+
+// findCharts returns every directory below root that contains a Chart.yaml, sorted.
+// Hidden directories and the charts/ directory of a chart (vendored subcharts) are skipped.
+func findCharts(root string) ([]string, error) {
+	var charts []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && len(d.Name()) > 1 && d.Name()[0] == '.' {
+			return filepath.SkipDir
+		}
+		if d.Name() == "charts" && path != root {
+			if _, err := os.Stat(filepath.Join(filepath.Dir(path), "Chart.yaml")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		if _, err := os.Stat(filepath.Join(path, "Chart.yaml")); err == nil {
+			charts = append(charts, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(charts)
+	return charts, nil
+}
+
+// loadChartConfig resolves the configuration of a chart directory the way the command
+// line configuration is resolved for the context directory. It is set while a command runs.
+var loadChartConfig func(dir string) (*config.Config, error)
+
+// resolveChartConfigs resolves the configuration of every chart into chartConfigs up front,
+// so that generating the charts only reads it. It returns the errors of the charts whose
+// config files are invalid, keyed by chart directory. Callers reset chartConfigs when done.
+func resolveChartConfigs(charts []string) map[string]error {
+	errs := make(map[string]error)
+	if loadChartConfig == nil {
+		return errs
+	}
+	chartConfigs = make(map[string]*config.Config, len(charts))
+	for _, chart := range charts {
+		c, err := loadChartConfig(chart)
+		if err != nil {
+			errs[chart] = err
+			continue
+		}
+		chartConfigs[absPath(chart)] = c
+	}
+	return errs
+}
+
+// GenerateAll generates the schema of every chart below root using at most jobs
+// concurrent workers. Each chart reads the config files discovered for its directory, and
+// the overrides file is resolved in each chart directory.
+// It returns one result per chart, in chart directory order.
+func GenerateAll(root, overridesFlag string, jobs int) ([]ChartResult, error) {
+	// Create context for tracing, joining the trace of the pipeline that started valet
+	ctx := telemetry.ContextFromEnv(context.Background())
+	tel := GetTelemetry()
+
+	start := time.Now()
+	ctx, span := tel.StartSpan(ctx, "generate.recursive",
+		trace.WithAttributes(
+			attribute.String("root", root),
+			attribute.Bool("has_overrides", overridesFlag != ""),
+			attribute.Int("jobs", jobs),
+		),
+	)
+	defer span.End()
+
+	charts, err := findCharts(root)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("error searching %s for charts: %w", root, err)
+	}
+	if len(charts) == 0 {
+		err := fmt.Errorf("no charts (directories with a Chart.yaml) found in %s", root)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	// A chart whose config files are invalid fails on its own
+	results := make([]ChartResult, len(charts))
+	configErrs := resolveChartConfigs(charts)
+	defer func() { chartConfigs = nil }()
+	for i, chart := range charts {
+		if err := configErrs[chart]; err != nil {
+			results[i] = ChartResult{Dir: chart, Err: err}
+		}
+	}
+
+	// Fan the charts out to a bounded pool of workers; each worker writes only its own result
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < len(charts); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if results[i].Err != nil {
+					continue
+				}
+				results[i] = generateChart(ctx, charts[i], overridesFlag)
+			}
+		}()
+	}
+	for i := range charts {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	failed := countFailed(results)
+	span.SetAttributes(
+		attribute.Int("charts", len(results)),
+		attribute.Int("failed", failed),
+	)
+	var runErr error
+	if failed > 0 {
+		runErr = fmt.Errorf("%d of %d charts failed", failed, len(results))
+		span.SetStatus(codes.Error, runErr.Error())
+	} else {
+		span.SetStatus(codes.Ok, "Schemas generated successfully")
+	}
+
+	// Record command metrics
+	if cmdMetrics, metricsErr := tel.NewCommandMetrics(); metricsErr == nil {
+		cmdMetrics.RecordCommandExecution(ctx, "generate", time.Since(start), runErr)
+	}
+	return results, nil
+}
+
+// generateChart generates and writes the schema of a single chart, with tracing
+func generateChart(ctx context.Context, ctxDir, overridesFlag string) ChartResult {
+	tel := GetTelemetry()
+	start := time.Now()
+	ctx, span := tel.StartSpan(ctx, "generate.chart",
+		trace.WithAttributes(attribute.String("context_dir", ctxDir)),
+	)
+	defer span.End()
+
+	result := ChartResult{Dir: ctxDir}
+	cache, upToDate := openCache(ctxDir, overridesFlag, outputPath(ctxDir, outputFormat(ctxDir)))
+	span.SetAttributes(attribute.Bool("cache_hit", upToDate))
+	if upToDate {
+		result.Cached = true
+		result.Output = outputPath(ctxDir, outputFormat(ctxDir))
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	schema, err := buildSchema(ctx, tel, ctxDir, overridesFlag)
+	if err == nil {
+		result.Properties = countSchemaFields(schema)
+		result.Output, err = writeSchema(ctx, tel, ctxDir, schema)
+	}
+	if err == nil {
+		cache.store(result.Output)
+	}
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Err = err
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return result
+}
+
+// countFailed counts the results with an error
+func countFailed(results []ChartResult) int {
+	n := 0
+	for _, r := range results {
+		if r.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// printSummary writes a table with the outcome of every chart, followed by the totals
+func printSummary(out io.Writer, results []ChartResult) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHART\tSTATUS\tPROPERTIES\tDURATION\tOUTPUT")
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(tw, "%s\tfailed\t-\t%s\t%v\n", r.Dir, r.Duration.Round(time.Millisecond), r.Err)
+			continue
+		}
+		if r.Cached {
+			fmt.Fprintf(tw, "%s\tcached\t-\t%s\t%s\n", r.Dir, r.Duration.Round(time.Millisecond), r.Output)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\tok\t%d\t%s\t%s\n", r.Dir, r.Properties, r.Duration.Round(time.Millisecond), r.Output)
+	}
+	tw.Flush()
+	cached := 0
+	for _, r := range results {
+		if r.Cached {
+			cached++
+		}
+	}
+	fmt.Fprintf(out, "%d charts, %d failed, %d up to date\n", len(results), countFailed(results), cached)
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// content-hash cache for the generate subcommand
+
+// cacheVersion is bumped when the layout of the input hash changes
+const cacheVersion = "1"
+
+// schemaCache records the input hash of a chart's generated schema. Cache problems
+// never fail a generation; they only make it regenerate.
+type schemaCache struct {
+	// entry is the cache file for the output path
+	entry string
+	// key is the hash of the current inputs
+	key string
+}
+
+// cacheEnabled reports whether the generate cache is turned on for ctxDir
+func cacheEnabled(ctxDir string) bool {
+	c := configFor(ctxDir)
+	return c != nil && c.Cache
+}
+
+// cacheDir returns the directory holding the cache entries, below the user cache
+// directory ($XDG_CACHE_HOME on Linux)
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "valet", "schemas"), nil
+}
+
+// openCache hashes the inputs of the schema of ctxDir written to outPath and reports
+// whether the schema on disk was generated from the same inputs. It returns a nil cache
+// when the cache is disabled or the inputs cannot be hashed.
+func openCache(ctxDir, overridesFlag, outPath string) (*schemaCache, bool) {
+	if !cacheEnabled(ctxDir) {
+		return nil, false
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		zap.L().Debug("Schema cache disabled", zap.Error(err))
+		return nil, false
+	}
+	key, err := inputHash(ctxDir, overridesFlag, outPath)
+	if err != nil {
+		zap.L().Debug("Schema cache disabled", zap.String("context_dir", ctxDir), zap.Error(err))
+		return nil, false
+	}
+	c := &schemaCache{entry: filepath.Join(dir, hashString(absPath(outPath))), key: key}
+
+	stored, err := os.ReadFile(c.entry)
+	if err != nil {
+		return c, false
+	}
+	fields := strings.Fields(string(stored))
+	if len(fields) != 2 || fields[0] != key {
+		return c, false
+	}
+	// The output must still be the file that was written, not deleted or edited by hand
+	output, err := hashFile(outPath)
+	return c, err == nil && output == fields[1]
+}
+
+// store records that outPath was generated from the current inputs
+func (c *schemaCache) store(outPath string) {
+	if c == nil {
+		return
+	}
+	output, err := hashFile(outPath)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(c.entry), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(c.entry, []byte(c.key+" "+output+"\n"), 0644)
+	}
+	if err != nil {
+		zap.L().Debug("Error writing schema cache entry", zap.String("file", c.entry), zap.Error(err))
+	}
+}
+
+// inputHash hashes everything the schema of ctxDir is generated from: the valet version,
+// the configuration that affects the output, and the values, overrides, overlay and
+// template files
+func inputHash(ctxDir, overridesFlag, outPath string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "cache %s\nversion %s\n", cacheVersion, GetBuildVersion())
+	fmt.Fprintf(h, "format %s\noutput %s\nstrict %s\nschema %s\n", outputFormat(ctxDir), absPath(outPath), strictMode(ctxDir), schemaURI(ctxDir))
+	// Rules are hashed in their YAML form
+	if rules := schemaRules(ctxDir); len(rules) > 0 {
+		data, err := yaml.Marshal(rules)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "rules %s\n", data)
+	}
+
+	files := []string{}
+	if valuesPath, err := findValuesFile(ctxDir); err == nil {
+		files = append(files, valuesPath)
+	}
+	if overridesFlag == "" {
+		overridesFlag = configuredOverrides(ctxDir)
+	}
+	if overridesFlag != "" {
+		files = append(files, filepath.Join(ctxDir, overridesFlag))
+	}
+	overlayPath, _ := resolveOverlayPath(ctxDir)
+	files = append(files, overlayPath)
+	// Subcharts are recognized by name, so the chart metadata is an input as well
+	files = append(files, filepath.Join(ctxDir, "Chart.yaml"))
+	// WalkDir visits the templates in lexical order, so the hash is stable
+	templates := filepath.Join(ctxDir, "templates")
+	err := filepath.WalkDir(templates, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == templates {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range files {
+		sum, err := hashFile(file)
+		if os.IsNotExist(err) {
+			sum = "missing"
+		} else if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "file %s %s\n", filepath.ToSlash(file), sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile returns the hex SHA-256 of the contents of path
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashString returns the hex SHA-256 of s
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mkm29/valet/internal/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// config subcommand
+
+// redacted replaces the values of OTLP headers, which usually hold credentials
+const redacted = "<redacted>"
+
+// showConfig writes the effective configuration as YAML. With origins set, every setting
+// is annotated with the file, environment variable or flag it came from.
+func showConfig(w io.Writer, c *config.Config, origins bool) error {
+	shown := *c
+	if c.Telemetry != nil {
+		telemetryCopy := *c.Telemetry
+		telemetryCopy.Headers = redactHeaders(c.Telemetry.Headers)
+		telemetryCopy.Tracing.Headers = redactHeaders(c.Telemetry.Tracing.Headers)
+		telemetryCopy.Metrics.Headers = redactHeaders(c.Telemetry.Metrics.Headers)
+		telemetryCopy.Logs.Headers = redactHeaders(c.Telemetry.Logs.Headers)
+		shown.Telemetry = &telemetryCopy
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(&shown); err != nil {
+		return fmt.Errorf("error marshaling config: %w", err)
+	}
+	if origins {
+		annotateOrigins(&doc, "", c)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("error marshaling config: %w", err)
+	}
+	return enc.Close()
+}
+
+// redactHeaders returns a copy of headers with every value redacted
+func redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	out := make(map[string]string, len(headers))
+	for k := range headers {
+		out[k] = redacted
+	}
+	return out
+}
+
+// annotateOrigins adds the origin of every setting below the mapping node at path as a line comment
+func annotateOrigins(node *yaml.Node, path string, c *config.Config) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		childPath := joinValuesPath(path, key.Value)
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+			annotateOrigins(value, childPath, c)
+			continue
+		}
+		value.LineComment = "# " + c.Origin(childPath)
+	}
+}
+
+// validateConfigFiles checks every config file on its own for YAML errors, unknown keys and
+// invalid settings, reporting each file. It returns the number of invalid files.
+func validateConfigFiles(w io.Writer, files []string) int {
+	invalid := 0
+	for _, file := range files {
+		if err := validateConfigFile(file); err != nil {
+			invalid++
+			fmt.Fprintf(w, "%s:\n", file)
+			for _, line := range strings.Split(err.Error(), "\n") {
+				fmt.Fprintf(w, "  %s\n", strings.TrimSpace(line))
+			}
+			continue
+		}
+		fmt.Fprintf(w, "%s: ok\n", file)
+	}
+	return invalid
+}
+
+// validateConfigFile checks a single config file
+func validateConfigFile(file string) error {
+	if _, err := os.Stat(file); err != nil {
+		return err
+	}
+	c, err := config.LoadFiles(file)
+	if err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the valet configuration",
+		Long: `Inspect the valet configuration.
+
+Without --config-file, valet merges the configuration files it discovers, later ones
+overriding earlier ones key by key:
+
+  ~/.valet.yaml
+  $XDG_CONFIG_HOME/valet/config.yaml (default: ~/.config/valet/config.yaml)
+  .valet.yaml in every directory from the repository root down to the context directory
+
+Environment variables (VALET_*, OTEL_*) override the files, and flags override both.`,
+	}
+	cmd.AddCommand(newConfigShowCmd())
+	cmd.AddCommand(newConfigValidateCmd())
+	return cmd
+}
+
+func newConfigShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show [context-dir]",
+		Short: "Print the effective configuration",
+		Long: `Print the effective configuration for a context directory (default: the working
+directory) as YAML. With --origin, every setting is annotated with the config file,
+environment variable or flag it came from. Telemetry header values are redacted.`,
+		Args: cobra.MaximumNArgs(1),
+		// Do not print usage on error; just show the error message
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			origins, err := cmd.Flags().GetBool("origin")
+			if err != nil {
+				return err
+			}
+			return showConfig(cmd.OutOrStdout(), cfg, origins)
+		},
+	}
+	cmd.Flags().Bool("origin", false, "annotate every setting with where it came from")
+	return cmd
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate [files...]",
+		Short: "Check config files for unknown keys and invalid settings",
+		Long: `Check config files for YAML errors, unknown keys and invalid settings. Every file is
+checked on its own and reported with the line numbers of its problems.
+
+Without arguments, the --config-file or the config files discovered for the working
+directory are checked. The command fails if any file is invalid.`,
+		Args: cobra.ArbitraryArgs,
+		// Do not print usage on error; just show the error message
+		SilenceUsage: true,
+		// Skip loading the configuration, which fails on the invalid files this reports
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := args
+			if len(files) == 0 {
+				if flags := cmd.Root().PersistentFlags(); flags.Changed("config-file") {
+					cfgFile, _ := flags.GetString("config-file")
+					files = []string{cfgFile}
+				} else {
+					files = config.DiscoverFiles(".")
+				}
+			}
+			if len(files) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No config files found")
+				return nil
+			}
+			if n := validateConfigFiles(cmd.OutOrStdout(), files); n > 0 {
+				return fmt.Errorf("%d of %d config file(s) invalid", n, len(files))
+			}
+			return nil
+		},
+	}
+}
@@ -1,14 +1,20 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
+	"strings"
 	"time"
 
+	"github.com/mkm29/valet/internal/config"
 	"github.com/mkm29/valet/internal/telemetry"
 	"github.com/spf13/cobra"
 	"go.opentelemetry.io/otel/attribute"
@@ -348,8 +354,15 @@ func convertToStringKeyMap(m interface{}) interface{} {
 	return m
 }
 
-// loadYAML reads a YAML file into map[string]any (empty if missing)
-func loadYAML(path string) (map[string]any, error) {
+// stderr receives the warnings of strict loading; override for testing
+var stderr io.Writer = os.Stderr
+
+// loadYAML reads a values file into map[string]any (empty if missing). Files with a .json
+// extension are read as JSON; the documents of a multi-document YAML file are merged in
+// order, later documents overriding earlier ones. In strict mode, duplicate keys,
+// yes/no/on/off booleans and ambiguous numbers in YAML are reported as warnings or errors
+// with their file position.
+func loadYAML(path, strict string) (map[string]any, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -357,25 +370,103 @@ func loadYAML(path string) (map[string]any, error) {
 		}
 		return nil, err
 	}
-	var m map[interface{}]interface{}
-	if err := yaml.Unmarshal(data, &m); err != nil {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return loadJSON(data)
+	}
+	return parseValues(path, data, strict)
+}
+
+// parseValues parses YAML values data read from the named source, merging its documents
+// and applying the strict mode
+func parseValues(name string, data []byte, mode string) (map[string]any, error) {
+	if mode != strictOff {
+		findings, err := checkStrict(name, data)
+		if err != nil {
+			return nil, err
+		}
+		if len(findings) > 0 {
+			severity := severityWarning
+			if mode == strictError {
+				severity = severityError
+			}
+			for i := range findings {
+				findings[i].Severity = severity
+			}
+			writeFindings(stderr, findings, reportText)
+			if mode == strictError {
+				first := findings[0]
+				more := ""
+				if len(findings) > 1 {
+					more = fmt.Sprintf(" (and %d more)", len(findings)-1)
+				}
+				return nil, fmt.Errorf("strict mode: line %d, column %d: %s%s", first.Line, first.Column, first.Message, more)
+			}
+		}
+	}
+	result := map[string]any{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for i := 1; ; i++ {
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if doc == nil {
+			// Empty document
+			continue
+		}
+		m, ok := doc.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("document %d is %s, expected a mapping of values", i, describeValue(doc))
+		}
+		// Convert to map[string]any
+		result = deepMerge(result, convertToStringKeyMap(m).(map[string]interface{}))
+	}
+	return result, nil
+}
+
+// loadJSON reads a JSON values file, which must hold an object
+func loadJSON(data []byte) (map[string]any, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return map[string]any{}, nil
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
 		return nil, err
 	}
-	if m == nil {
+	if doc == nil {
 		return map[string]any{}, nil
 	}
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("top-level value is %s, expected an object of values", describeValue(doc))
+	}
+	return m, nil
+}
 
-	// Convert to map[string]any
-	result := convertToStringKeyMap(m).(map[string]interface{})
-	return result, nil
+// describeValue names the kind of a decoded YAML or JSON value for error messages
+func describeValue(v any) string {
+	switch v.(type) {
+	case []interface{}:
+		return "a list"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case int, int64, uint64, float64:
+		return "a number"
+	}
+	return fmt.Sprintf("a %T", v)
 }
 
 // Generate a JSON Schema for the values.yaml in ctx directory,
 // optionally merging an overrides YAML file relative to ctx.
 // It writes the schema to values.schema.json and returns a status message.
 func Generate(ctxDir, overridesFlag string) (string, error) {
-	// Create context for tracing
-	ctx := context.Background()
+	// Create context for tracing, joining the trace of the pipeline that started valet
+	ctx := telemetry.ContextFromEnv(context.Background())
 	tel := GetTelemetry()
 
 	// Start main span
@@ -420,28 +511,192 @@ func Generate(ctxDir, overridesFlag string) (string, error) {
 
 // generateInternal contains the actual generation logic
 func generateInternal(ctx context.Context, tel *telemetry.Telemetry, ctxDir, overridesFlag string) (string, error) {
-	// Locate values file (values.yaml or values.yml)
-	valuesPath := filepath.Join(ctxDir, "values.yaml")
-	if _, err := os.Stat(valuesPath); os.IsNotExist(err) {
-		alt := filepath.Join(ctxDir, "values.yml")
-		if _, err2 := os.Stat(alt); os.IsNotExist(err2) {
-			return "", fmt.Errorf("no values.yaml or values.yml found in %s", ctxDir)
+	cache, upToDate := openCache(ctxDir, overridesFlag, outputPath(ctxDir, outputFormat(ctxDir)))
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache_hit", upToDate))
+	if upToDate {
+		return fmt.Sprintf("%s is up to date", outputPath(ctxDir, outputFormat(ctxDir))), nil
+	}
+
+	schema, err := buildSchema(ctx, tel, ctxDir, overridesFlag)
+	if err != nil {
+		return "", err
+	}
+
+	outPath, err := writeSchema(ctx, tel, ctxDir, schema)
+	if err != nil {
+		return "", err
+	}
+	cache.store(outPath)
+
+	valuesName := "values.yaml"
+	if valuesPath, err := findValuesFile(ctxDir); err == nil {
+		valuesName = filepath.Base(valuesPath)
+	}
+	if overridesFlag != "" {
+		return fmt.Sprintf("Generated %s by merging %s into %s", outPath, overridesFlag, valuesName), nil
+	}
+	return fmt.Sprintf("Generated %s from %s", outPath, valuesName), nil
+}
+
+// stdinName names values read from standard input in messages and findings
+const stdinName = "<stdin>"
+
+// GenerateStream generates a schema for the values read from r, skipping the chart
+// directory lookup, and writes it to w, or to the output file given with --output.
+// A relative overrides file or output file is resolved against the working directory.
+func GenerateStream(r io.Reader, w io.Writer, overridesFlag string) error {
+	// Create context for tracing, joining the trace of the pipeline that started valet
+	ctx := telemetry.ContextFromEnv(context.Background())
+	tel := GetTelemetry()
+
+	// Start main span
+	start := time.Now()
+	ctx, span := tel.StartSpan(ctx, "generate.command",
+		trace.WithAttributes(
+			attribute.String("context_dir", "-"),
+			attribute.Bool("has_overrides", overridesFlag != ""),
+		),
+	)
+	defer span.End()
+
+	err := generateStream(ctx, tel, r, w, overridesFlag)
+	if tel.IsEnabled() {
+		// Record command metrics
+		if cmdMetrics, metricsErr := tel.NewCommandMetrics(); metricsErr == nil {
+			cmdMetrics.RecordCommandExecution(ctx, "generate", time.Since(start), err)
 		}
-		valuesPath = alt
+
+		// Set span status
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetStatus(codes.Ok, "Schema generated successfully")
+		}
+	}
+	return err
+}
+
+// generateStream contains the generation logic for values read from a stream
+func generateStream(ctx context.Context, tel *telemetry.Telemetry, r io.Reader, w io.Writer, overridesFlag string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", stdinName, err)
+	}
+
+	// Load values with tracing; a JSON object is read as JSON, anything else as YAML
+	ctx, loadSpan := tel.StartSpan(ctx, "load.values_yaml",
+		trace.WithAttributes(attribute.String("file", stdinName)),
+	)
+	var values map[string]any
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed) {
+		values, err = loadJSON(data)
+	} else {
+		values, err = parseValues(stdinName, data, strictMode(""))
+	}
+	loadSpan.End()
+	if err != nil {
+		telemetry.RecordError(ctx, err)
+		return fmt.Errorf("error loading %s: %w", stdinName, err)
+	}
+
+	layout := parseValuesLayout(data)
+	annotations := schemaAnnotations(stdinName, layout)
+
+	schema, err := schemaFromValues(ctx, tel, "", values, annotations, overridesFlag)
+	if err != nil {
+		return err
+	}
+
+	// Only --output writes the schema to a file: the output of a discovered config file
+	// names the schema of a chart, which the schema of a stream must not replace
+	toFile := cfg != nil && cfg.Output != "" && strings.HasPrefix(cfg.Origin("output"), "flag ")
+	format := formatJSON
+	// Without an output file, Go types are named after the working directory
+	outPath := stdinName
+	if toFile {
+		format = outputFormat("")
+		outPath = outputPath("", format)
+	} else if cfg != nil && cfg.Format != "" {
+		format = cfg.Format
+	}
+
+	ctx, marshalSpan := tel.StartSpan(ctx, "marshal."+format)
+	out, err := renderSchema(schema, format, layout, outPath)
+	marshalSpan.End()
+	if err != nil {
+		telemetry.RecordError(ctx, err)
+		return err
+	}
+
+	if toFile {
+		if err := os.WriteFile(outPath, out, 0644); err != nil {
+			telemetry.RecordError(ctx, err)
+			return fmt.Errorf("error writing %s: %w", outPath, err)
+		}
+		return nil
+	}
+	if _, err := w.Write(out); err != nil {
+		return fmt.Errorf("error writing schema: %w", err)
+	}
+	return nil
+}
+
+// valuesFileNames are the values files looked up in the context directory, in order
+var valuesFileNames = []string{"values.yaml", "values.yml", "values.json"}
+
+// findValuesFile locates the values file in ctxDir: the configured values file, or else
+// the first of values.yaml, values.yml and values.json that exists
+func findValuesFile(ctxDir string) (string, error) {
+	if c := configFor(ctxDir); c != nil && c.Values != "" {
+		valuesPath := c.Values
+		if !filepath.IsAbs(valuesPath) {
+			valuesPath = filepath.Join(ctxDir, valuesPath)
+		}
+		if _, err := os.Stat(valuesPath); err != nil {
+			return "", fmt.Errorf("values file %s not found", valuesPath)
+		}
+		return valuesPath, nil
+	}
+	for _, name := range valuesFileNames {
+		valuesPath := filepath.Join(ctxDir, name)
+		if _, err := os.Stat(valuesPath); err == nil {
+			return valuesPath, nil
+		}
+	}
+	return "", fmt.Errorf("no values.yaml, values.yml or values.json found in %s", ctxDir)
+}
+
+// buildSchema loads the values file in ctxDir, merges the optional overrides file
+// and returns the inferred schema with the schema overlay applied.
+func buildSchema(ctx context.Context, tel *telemetry.Telemetry, ctxDir, overridesFlag string) (map[string]any, error) {
+	valuesPath, err := findValuesFile(ctxDir)
+	if err != nil {
+		return nil, err
+	}
+	if overridesFlag == "" {
+		overridesFlag = configuredOverrides(ctxDir)
 	}
 	var overridesPath string
 	if overridesFlag != "" {
 		overridesPath = filepath.Join(ctxDir, overridesFlag)
+		if _, err := os.Stat(overridesPath); err != nil {
+			return nil, fmt.Errorf("overrides file %s not found in %s", overridesFlag, ctxDir)
+		}
 	}
 	// Load main values file with tracing
 	ctx, loadSpan := tel.StartSpan(ctx, "load.values_yaml",
 		trace.WithAttributes(attribute.String("file", valuesPath)),
 	)
-	yaml1, err := loadYAML(valuesPath)
+	yaml1, err := loadYAML(valuesPath, strictMode(ctxDir))
+	var annotations map[string]any
+	if err == nil {
+		annotations = schemaAnnotations(valuesPath, loadValuesLayout(valuesPath))
+	}
 	loadSpan.End()
 	if err != nil {
 		telemetry.RecordError(ctx, err)
-		return "", fmt.Errorf("error loading %s: %w", valuesPath, err)
+		return nil, fmt.Errorf("error loading %s: %w", valuesPath, err)
 	}
 
 	// Record file metrics
@@ -488,17 +743,26 @@ func generateInternal(ctx context.Context, tel *telemetry.Telemetry, ctxDir, ove
 		)
 	}
 
+	return schemaFromValues(ctx, tel, ctxDir, yaml1, annotations, overridesPath)
+}
+
+// schemaFromValues merges the optional overrides file into the loaded values and returns
+// the inferred schema with the config rules and the @schema annotations of the values file
+// applied. With a chart directory, values read by its templates are added and its schema
+// overlay is applied; without one (values read from stdin) only an explicitly configured
+// overlay is.
+func schemaFromValues(ctx context.Context, tel *telemetry.Telemetry, ctxDir string, yaml1, annotations map[string]any, overridesPath string) (map[string]any, error) {
 	var merged map[string]any
 	if overridesPath != "" {
 		// Load overrides file with tracing
 		ctx, overrideSpan := tel.StartSpan(ctx, "load.overrides_yaml",
 			trace.WithAttributes(attribute.String("file", overridesPath)),
 		)
-		yaml2, err := loadYAML(overridesPath)
+		yaml2, err := loadYAML(overridesPath, strictMode(ctxDir))
 		overrideSpan.End()
 		if err != nil {
 			telemetry.RecordError(ctx, err)
-			return "", fmt.Errorf("error loading %s: %w", overridesPath, err)
+			return nil, fmt.Errorf("error loading %s: %w", overridesPath, err)
 		}
 
 		// Merge with tracing
@@ -513,29 +777,127 @@ func generateInternal(ctx context.Context, tel *telemetry.Telemetry, ctxDir, ove
 	ctx, schemaSpan := tel.StartSpan(ctx, "generate.schema")
 	schemaStart := time.Now()
 	schema := inferSchema(merged, yaml1)
-	schema["$schema"] = "http://json-schema.org/schema#"
+	schema["$schema"] = schemaURI(ctxDir)
 
 	// Post-process the schema to ensure no empty fields are in the required lists
 	cleanupRequiredFields(schema, yaml1)
 	schemaSpan.End()
 
+	if ctxDir != "" {
+		// Add values read by the chart templates but missing from values.yaml, with tracing
+		ctx, templatesSpan := tel.StartSpan(ctx, "scan.templates")
+		refs, err := scanTemplates(ctxDir)
+		if err != nil {
+			templatesSpan.End()
+			telemetry.RecordError(ctx, err)
+			return nil, err
+		}
+		added := addTemplateRefs(schema, refs)
+		templatesSpan.SetAttributes(
+			attribute.Int("references", len(refs)),
+			attribute.Int("added_properties", added),
+		)
+		templatesSpan.End()
+		if added > 0 {
+			zap.L().Debug("Added properties referenced by chart templates",
+				zap.Int("references", len(refs)),
+				zap.Int("added_properties", added))
+		}
+	}
+
+	// Apply the rules of the config files, with tracing
+	if rules := schemaRules(ctxDir); len(rules) > 0 {
+		_, rulesSpan := tel.StartSpan(ctx, "apply.schema_rules",
+			trace.WithAttributes(attribute.Int("rules", len(rules))),
+		)
+		applied := applyRules(schema, rules)
+		rulesSpan.SetAttributes(attribute.Int("matched_properties", applied))
+		rulesSpan.End()
+	}
+
+	// Apply the @schema annotations of the values file, with tracing
+	if len(annotations) > 0 {
+		ctx, annotationsSpan := tel.StartSpan(ctx, "apply.schema_annotations",
+			trace.WithAttributes(attribute.Int("annotations", len(annotations))),
+		)
+		err := applyOverlay(schema, annotations)
+		annotationsSpan.End()
+		if err != nil {
+			telemetry.RecordError(ctx, err)
+			return nil, fmt.Errorf("error applying %s annotations: %w", annotationMarker, err)
+		}
+	}
+
+	// Apply the sidecar schema overlay, if any, with tracing
+	var overlay map[string]any
+	var overlayPath string
+	var err error
+	if c := configFor(ctxDir); ctxDir != "" || (c != nil && c.SchemaOverlay != "") {
+		overlay, overlayPath, err = loadOverlay(ctxDir)
+		if err != nil {
+			telemetry.RecordError(ctx, err)
+			return nil, fmt.Errorf("error loading %s: %w", overlayPath, err)
+		}
+	}
+	if overlay != nil {
+		ctx, overlaySpan := tel.StartSpan(ctx, "apply.schema_overlay",
+			trace.WithAttributes(attribute.String("file", overlayPath)),
+		)
+		err := applyOverlay(schema, overlay)
+		overlaySpan.End()
+		if err != nil {
+			telemetry.RecordError(ctx, err)
+			return nil, fmt.Errorf("error applying %s: %w", overlayPath, err)
+		}
+	}
+
 	// Record schema generation metrics
 	if schemaMetrics, metricsErr := tel.NewSchemaGenerationMetrics(); metricsErr == nil {
 		fieldCount := countSchemaFields(schema)
 		schemaMetrics.RecordSchemaGeneration(ctx, int64(fieldCount), time.Since(schemaStart), nil)
 	}
 
-	outPath := filepath.Join(ctxDir, "values.schema.json")
+	return schema, nil
+}
+
+// writeSchema marshals schema and writes it to the output file in ctxDir,
+// returning the path that was written.
+func writeSchema(ctx context.Context, tel *telemetry.Telemetry, ctxDir string, schema map[string]any) (string, error) {
+	outPath, data, err := marshalSchema(ctx, tel, ctxDir, schema)
+	if err != nil {
+		return "", err
+	}
+	if err := writeSchemaFile(ctx, tel, outPath, data); err != nil {
+		return "", err
+	}
+	return outPath, nil
+}
 
-	// Marshal JSON with tracing
-	ctx, marshalSpan := tel.StartSpan(ctx, "marshal.json")
-	data, err := json.MarshalIndent(schema, "", "  ")
+// marshalSchema renders schema in the configured output format, returning the output
+// file in ctxDir and its contents.
+func marshalSchema(ctx context.Context, tel *telemetry.Telemetry, ctxDir string, schema map[string]any) (string, []byte, error) {
+	format := outputFormat(ctxDir)
+	outPath := outputPath(ctxDir, format)
+
+	// Marshal with tracing
+	layout := &valuesLayout{}
+	if format == formatYAML {
+		if valuesPath, err := findValuesFile(ctxDir); err == nil {
+			layout = loadValuesLayout(valuesPath)
+		}
+	}
+	ctx, marshalSpan := tel.StartSpan(ctx, "marshal."+format)
+	data, err := renderSchema(schema, format, layout, outPath)
 	marshalSpan.End()
 	if err != nil {
 		telemetry.RecordError(ctx, err)
-		return "", fmt.Errorf("error marshaling JSON: %w", err)
+		return "", nil, err
 	}
+	return outPath, data, nil
+}
 
+// writeSchemaFile writes the rendered schema to outPath
+func writeSchemaFile(ctx context.Context, tel *telemetry.Telemetry, outPath string, data []byte) error {
 	// Write file with tracing
 	ctx, writeSpan := tel.StartSpan(ctx, "write.schema_file",
 		trace.WithAttributes(
@@ -546,7 +908,7 @@ func generateInternal(ctx context.Context, tel *telemetry.Telemetry, ctxDir, ove
 	if err := os.WriteFile(outPath, data, 0644); err != nil {
 		writeSpan.End()
 		telemetry.RecordError(ctx, err)
-		return "", fmt.Errorf("error writing %s: %w", outPath, err)
+		return fmt.Errorf("error writing %s: %w", outPath, err)
 	}
 	writeSpan.End()
 
@@ -555,10 +917,101 @@ func generateInternal(ctx context.Context, tel *telemetry.Telemetry, ctxDir, ove
 		fileMetrics.RecordFileWrite(ctx, outPath, int64(len(data)), nil)
 	}
 
-	if overridesPath != "" {
-		return fmt.Sprintf("Generated %s by merging %s into values.yaml", outPath, overridesFlag), nil
+	return nil
+}
+
+// Supported output formats
+const (
+	formatJSON       = "json"
+	formatYAML       = "yaml"
+	formatTypeScript = "typescript"
+	formatGo         = "go"
+)
+
+// outputFormat returns the configured output format. Without one, the format is
+// inferred from the extension of the output file configured for ctxDir (default: json).
+func outputFormat(ctxDir string) string {
+	c := configFor(ctxDir)
+	if c == nil {
+		return formatJSON
+	}
+	if c.Format != "" {
+		return c.Format
+	}
+	if format := extensionFormat(configuredOutput(ctxDir)); format != "" {
+		return format
+	}
+	return formatJSON
+}
+
+// extensionFormat returns the output format of a file by its extension, or "" when the
+// extension belongs to no format
+func extensionFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return formatJSON
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".ts":
+		return formatTypeScript
+	case ".go":
+		return formatGo
+	}
+	return ""
+}
+
+// validateFormat returns an error for unsupported output formats
+func validateFormat(format string) error {
+	switch format {
+	case "", formatJSON, formatYAML, formatTypeScript, formatGo:
+		return nil
 	}
-	return fmt.Sprintf("Generated %s from values.yaml", outPath), nil
+	return fmt.Errorf("unsupported output format %q (expected json, yaml, typescript or go)", format)
+}
+
+// outputPath returns the file the output is written to. A relative output is resolved
+// against ctxDir; without one, the default file name depends on the format.
+func outputPath(ctxDir, format string) string {
+	if output := configuredOutput(ctxDir); output != "" {
+		if filepath.IsAbs(output) {
+			return output
+		}
+		return filepath.Join(ctxDir, output)
+	}
+	switch format {
+	case formatYAML:
+		return filepath.Join(ctxDir, "values.schema.yaml")
+	case formatTypeScript:
+		return filepath.Join(ctxDir, "values.ts")
+	case formatGo:
+		return filepath.Join(ctxDir, "values.go")
+	}
+	return filepath.Join(ctxDir, "values.schema.json")
+}
+
+// renderSchema serializes schema in the given format. YAML output follows the key
+// order and comments recorded in layout; Go output is named after the package of outPath.
+// An outPath with the extension of another format, such as Go source in a .json file, is
+// an error.
+func renderSchema(schema map[string]any, format string, layout *valuesLayout, outPath string) ([]byte, error) {
+	if ext := extensionFormat(outPath); ext != "" && ext != format && validateFormat(format) == nil {
+		return nil, fmt.Errorf("output file %s does not match the %s output format; set --output or --format to match", outPath, format)
+	}
+	switch format {
+	case formatJSON:
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling JSON: %w", err)
+		}
+		return data, nil
+	case formatYAML:
+		return schemaYAML(schema, layout)
+	case formatTypeScript:
+		return typeScriptTypes(schema, "Values"), nil
+	case formatGo:
+		return goTypes(schema, goPackageName(filepath.Base(filepath.Dir(absPath(outPath)))), "Values")
+	}
+	return nil, validateFormat(format)
 }
 
 // countSchemaFields counts the number of fields in a schema recursively
@@ -720,8 +1173,22 @@ func NewGenerateCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "generate <context-dir>",
 		Short: "Generate JSON Schema from values.yaml",
-		Long:  `Generate JSON Schema from values.yaml, optionally merging an overrides YAML file.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Generate JSON Schema from values.yaml, optionally merging an overrides YAML file.
+
+With "-" as the context directory, the values are read from stdin and the schema is
+written to stdout (or to --output, if given), for use in shell pipelines:
+
+  helm show values bitnami/nginx | valet generate - > values.schema.json
+
+Chart templates and the sidecar schema overlay are not looked up in this mode; an
+overrides file, output file or schema overlay is resolved against the working directory.
+
+With --recursive, the context directory is searched for charts (directories containing a
+Chart.yaml) and the schema of every chart is generated in parallel, using up to --jobs
+workers. The overrides file is applied in each chart that has it, and the output file is
+resolved in each chart directory. A summary table is printed, and the command fails if
+any chart fails.`,
+		Args: cobra.ExactArgs(1),
 		// Do not print usage on error; just show the error message
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -731,12 +1198,76 @@ func NewGenerateCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			watch, err := cmd.Flags().GetBool("watch")
+			if err != nil {
+				return err
+			}
+			recursive, err := cmd.Flags().GetBool("recursive")
+			if err != nil {
+				return err
+			}
+			if recursive {
+				if watch {
+					return fmt.Errorf("--watch cannot be used with --recursive")
+				}
+				if ctx == "-" {
+					return fmt.Errorf("--recursive cannot be used when reading values from stdin")
+				}
+				if cfg != nil && filepath.IsAbs(cfg.Output) {
+					return fmt.Errorf("--output must be relative to each chart directory with --recursive")
+				}
+				jobs, err := cmd.Flags().GetInt("jobs")
+				if err != nil {
+					return err
+				}
+				results, err := GenerateAll(ctx, overridesFlag, jobs)
+				if err != nil {
+					return err
+				}
+				printSummary(cmd.OutOrStdout(), results)
+				if failed := countFailed(results); failed > 0 {
+					return fmt.Errorf("%d of %d charts failed", failed, len(results))
+				}
+				return nil
+			}
+			if ctx == "-" {
+				if watch {
+					return fmt.Errorf("--watch cannot be used when reading values from stdin")
+				}
+				if overridesFlag != "" {
+					if _, err := os.Stat(overridesFlag); err != nil {
+						return fmt.Errorf("overrides file %s not found", overridesFlag)
+					}
+				}
+				return GenerateStream(cmd.InOrStdin(), cmd.OutOrStdout(), overridesFlag)
+			}
 			if overridesFlag != "" {
 				overridePath := filepath.Join(ctx, overridesFlag)
 				if _, err := os.Stat(overridePath); err != nil {
 					return fmt.Errorf("overrides file %s not found in %s", overridesFlag, ctx)
 				}
 			}
+			if watch {
+				opts := WatchOptions{
+					Out: cmd.OutOrStdout(),
+					// Reload every config file when one of them changes, as they are merged
+					Reload: func() error {
+						c, err := initializeConfig(cmd.Root(), ctx)
+						if err != nil {
+							return err
+						}
+						cfg = c
+						return nil
+					},
+				}
+				// Watch the config files the configuration was read from
+				if f := cmd.Flags().Lookup("config-file"); f != nil && f.Changed {
+					opts.ConfigFiles = []string{f.Value.String()}
+				} else {
+					opts.ConfigFiles = config.DiscoverFiles(ctx)
+				}
+				return Watch(cmd.Context(), ctx, overridesFlag, opts)
+			}
 			msg, err := Generate(ctx, overridesFlag)
 			if err != nil {
 				return err
@@ -746,5 +1277,8 @@ func NewGenerateCmd() *cobra.Command {
 		},
 	}
 	cmd.Flags().StringP("overrides", "f", "", "path (relative to context dir) to overrides YAML (optional)")
+	cmd.Flags().BoolP("watch", "w", false, "watch the input files and regenerate the schema when they change")
+	cmd.Flags().BoolP("recursive", "r", false, "generate the schema of every chart below the context directory")
+	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "number of charts generated in parallel with --recursive")
 	return cmd
 }
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mkm29/valet/internal/telemetry"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// hook subcommand for pre-commit and lefthook
+
+// Outcomes of checking the schema of a chart in a hook run
+const (
+	hookUnchanged = "unchanged"
+	hookUpdated   = "updated"
+	hookOutdated  = "out of date"
+	hookFailed    = "failed"
+)
+
+// HookResult is the outcome of checking the schema of one chart owning changed files
+type HookResult struct {
+	// Dir is the chart directory
+	Dir string
+	// Output is the schema file of the chart
+	Output string
+	// Status is one of unchanged, updated, out of date or failed
+	Status string
+	// Err is the error that made the chart fail, if any
+	Err error
+}
+
+// hookCharts maps changed files to the sorted, distinct chart directories owning them.
+// Files outside of any chart are ignored.
+func hookCharts(files []string) []string {
+	seen := make(map[string]bool)
+	var charts []string
+	for _, file := range files {
+		dir := findChartDir(filepath.Dir(file))
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		charts = append(charts, dir)
+	}
+	sort.Strings(charts)
+	return charts
+}
+
+// Hook regenerates the schemas of the charts owning the changed files. With check set,
+// schemas that differ are reported but not written.
+func Hook(files []string, check bool) []HookResult {
+	// Create context for tracing, joining the trace of the pipeline that started valet
+	ctx := telemetry.ContextFromEnv(context.Background())
+	tel := GetTelemetry()
+
+	start := time.Now()
+	ctx, span := tel.StartSpan(ctx, "hook.command",
+		trace.WithAttributes(
+			attribute.Int("files", len(files)),
+			attribute.Bool("check", check),
+		),
+	)
+	defer span.End()
+
+	// Every chart reads the config files discovered for its directory
+	charts := hookCharts(files)
+	configErrs := resolveChartConfigs(charts)
+	defer func() { chartConfigs = nil }()
+	var results []HookResult
+	for _, dir := range charts {
+		if err := configErrs[dir]; err != nil {
+			results = append(results, HookResult{Dir: dir, Status: hookFailed, Err: err})
+			continue
+		}
+		results = append(results, hookChart(ctx, dir, check))
+	}
+
+	changed := countChanged(results)
+	span.SetAttributes(
+		attribute.Int("charts", len(results)),
+		attribute.Int("changed", changed),
+	)
+	var runErr error
+	if changed > 0 {
+		runErr = fmt.Errorf("%d schema(s) changed", changed)
+		span.SetStatus(codes.Error, runErr.Error())
+	} else {
+		span.SetStatus(codes.Ok, "Schemas up to date")
+	}
+
+	// Record command metrics
+	if cmdMetrics, metricsErr := tel.NewCommandMetrics(); metricsErr == nil {
+		cmdMetrics.RecordCommandExecution(ctx, "hook", time.Since(start), runErr)
+	}
+	return results
+}
+
+// hookChart regenerates the schema of a chart and compares it with the file on disk
+func hookChart(ctx context.Context, dir string, check bool) HookResult {
+	tel := GetTelemetry()
+	ctx, span := tel.StartSpan(ctx, "hook.chart",
+		trace.WithAttributes(attribute.String("context_dir", dir)),
+	)
+	defer span.End()
+
+	result := HookResult{Dir: dir, Output: outputPath(dir, outputFormat(dir))}
+	fail := func(err error) HookResult {
+		result.Status = hookFailed
+		result.Err = err
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return result
+	}
+
+	cache, upToDate := openCache(dir, "", result.Output)
+	if upToDate {
+		result.Status = hookUnchanged
+		return result
+	}
+	schema, err := buildSchema(ctx, tel, dir, "")
+	if err != nil {
+		return fail(err)
+	}
+	outPath, data, err := marshalSchema(ctx, tel, dir, schema)
+	if err != nil {
+		return fail(err)
+	}
+	if existing, err := os.ReadFile(outPath); err == nil && bytes.Equal(existing, data) {
+		cache.store(outPath)
+		result.Status = hookUnchanged
+		return result
+	}
+	if check {
+		result.Status = hookOutdated
+		return result
+	}
+	if err := writeSchemaFile(ctx, tel, outPath, data); err != nil {
+		return fail(err)
+	}
+	cache.store(outPath)
+	result.Status = hookUpdated
+	return result
+}
+
+// countChanged counts the results that fail the hook: schemas that were updated or
+// are out of date, and charts that failed
+func countChanged(results []HookResult) int {
+	n := 0
+	for _, r := range results {
+		if r.Status != hookUnchanged {
+			n++
+		}
+	}
+	return n
+}
+
+// printHookResults reports the charts whose schema changed or failed
+func printHookResults(out io.Writer, results []HookResult) {
+	for _, r := range results {
+		switch r.Status {
+		case hookUpdated:
+			fmt.Fprintf(out, "Updated %s\n", displayPath(r.Output))
+		case hookOutdated:
+			fmt.Fprintf(out, "%s is out of date\n", displayPath(r.Output))
+		case hookFailed:
+			fmt.Fprintf(out, "Error: %s: %v\n", displayPath(r.Dir), r.Err)
+		}
+	}
+}
+
+// displayPath returns path relative to the working directory when it is below it
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, absPath(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
+
+func NewHookCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "hook [files...]",
+		Aliases: []string{"pre-commit"},
+		Short:   "Regenerate the schemas of the charts owning changed files",
+		Long: `Regenerate the schemas of the charts owning the given files, for use as a
+pre-commit or lefthook hook.
+
+Each file is mapped to its chart: the nearest directory at or above it containing a
+Chart.yaml. Only those charts are regenerated. The command fails if any schema changed,
+so that the commit is stopped and the updated schemas can be staged. With --check,
+schemas that are out of date are reported but not written.`,
+		Args: cobra.ArbitraryArgs,
+		// Do not print usage on error; just show the error message
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			check, err := cmd.Flags().GetBool("check")
+			if err != nil {
+				return err
+			}
+			results := Hook(args, check)
+			printHookResults(cmd.OutOrStdout(), results)
+			n := countChanged(results)
+			if n == 0 {
+				return nil
+			}
+			if check {
+				return fmt.Errorf("%d schema(s) out of date or failed; run valet hook or valet generate", n)
+			}
+			return fmt.Errorf("%d schema(s) updated or failed; review and stage the changes", n)
+		},
+	}
+	cmd.Flags().Bool("check", false, "report schemas that are out of date without writing them")
+	return cmd
+}
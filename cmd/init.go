@@ -0,0 +1,476 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mkm29/valet/internal/config"
+	"github.com/mkm29/valet/internal/telemetry"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
+)
+
+// init subcommand: scaffolds the configuration of a chart
+
+// configSchemaURL is the published JSON Schema of the config file
+const configSchemaURL = "https://raw.githubusercontent.com/mkm29/valet/main/schemas/valet-config.schema.json"
+
+// enumCandidate is a values path pattern whose values usually come from a fixed set
+type enumCandidate struct {
+	pattern []string
+	values  []string
+}
+
+// enumCandidates are the well-known Kubernetes and chart settings detected as enums. A value
+// is only a candidate when its current value is one of the set.
+var enumCandidates = []enumCandidate{
+	{splitValuesPath("**.pullPolicy"), []string{"Always", "IfNotPresent", "Never"}},
+	{splitValuesPath("**.imagePullPolicy"), []string{"Always", "IfNotPresent", "Never"}},
+	{splitValuesPath("**.service.type"), []string{"ClusterIP", "NodePort", "LoadBalancer", "ExternalName"}},
+	{splitValuesPath("**.externalTrafficPolicy"), []string{"Cluster", "Local"}},
+	{splitValuesPath("**.internalTrafficPolicy"), []string{"Cluster", "Local"}},
+	{splitValuesPath("**.sessionAffinity"), []string{"None", "ClientIP"}},
+	{splitValuesPath("**.protocol"), []string{"TCP", "UDP", "SCTP"}},
+	{splitValuesPath("**.pathType"), []string{"Exact", "Prefix", "ImplementationSpecific"}},
+	{splitValuesPath("**.strategy.type"), []string{"RollingUpdate", "Recreate"}},
+	{splitValuesPath("**.updateStrategy.type"), []string{"RollingUpdate", "OnDelete"}},
+	{splitValuesPath("**.restartPolicy"), []string{"Always", "OnFailure", "Never"}},
+	{splitValuesPath("**.dnsPolicy"), []string{"ClusterFirst", "ClusterFirstWithHostNet", "Default", "None"}},
+	{splitValuesPath("**.concurrencyPolicy"), []string{"Allow", "Forbid", "Replace"}},
+	{splitValuesPath("**.logLevel"), []string{"debug", "info", "warn", "error"}},
+}
+
+// InitResult is the outcome of scaffolding the configuration of a chart
+type InitResult struct {
+	// Config is the config file that was written, or "" when an existing one was kept
+	Config string
+	// Subcharts are the names of the chart's subcharts, sorted
+	Subcharts []string
+	// Enums are the values paths detected as candidate enums
+	Enums []string
+	// Ambiguous are the values paths whose type cannot be inferred from their value:
+	// empty strings, empty maps and lists, and nulls
+	Ambiguous []string
+	// Annotated is the number of @schema placeholders inserted into the values file
+	Annotated int
+}
+
+// valuesKey is a key of a values file, with the node of its value
+type valuesKey struct {
+	path  string
+	key   *yaml.Node
+	value *yaml.Node
+	// annotatable reports whether a comment line can be inserted above the key: it is in a
+	// block mapping outside of any sequence
+	annotatable bool
+}
+
+// InitChart writes a .valet.yaml tuned to the chart in ctxDir. With annotate set, @schema
+// placeholders are inserted into the values file above the keys whose type is ambiguous,
+// and an existing .valet.yaml is kept unless force is set.
+func InitChart(ctxDir string, force, annotate bool) (*InitResult, error) {
+	// Create context for tracing, joining the trace of the pipeline that started valet
+	ctx := telemetry.ContextFromEnv(context.Background())
+	tel := GetTelemetry()
+
+	start := time.Now()
+	ctx, span := tel.StartSpan(ctx, "init.command",
+		trace.WithAttributes(
+			attribute.String("context_dir", ctxDir),
+			attribute.Bool("annotate", annotate),
+		),
+	)
+	defer span.End()
+
+	result, err := initChart(ctxDir, force, annotate)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(
+			attribute.Int("enums", len(result.Enums)),
+			attribute.Int("ambiguous", len(result.Ambiguous)),
+			attribute.Int("annotated", result.Annotated),
+		)
+		span.SetStatus(codes.Ok, "Chart initialized")
+	}
+
+	// Record command metrics
+	if cmdMetrics, metricsErr := tel.NewCommandMetrics(); metricsErr == nil {
+		cmdMetrics.RecordCommandExecution(ctx, "init", time.Since(start), err)
+	}
+	return result, err
+}
+
+// initChart contains the logic of InitChart
+func initChart(ctxDir string, force, annotate bool) (*InitResult, error) {
+	valuesPath, err := findValuesFile(ctxDir)
+	if err != nil {
+		return nil, err
+	}
+	if annotate && strings.EqualFold(filepath.Ext(valuesPath), ".json") {
+		return nil, fmt.Errorf("--annotate needs a YAML values file, got %s", valuesPath)
+	}
+	configPath := filepath.Join(ctxDir, config.FileName)
+	_, statErr := os.Stat(configPath)
+	writeConfig := force || os.IsNotExist(statErr)
+	if !writeConfig && !annotate {
+		return nil, fmt.Errorf("%s already exists; use --force to overwrite it", configPath)
+	}
+
+	data, err := os.ReadFile(valuesPath)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := parseDocuments(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", valuesPath, err)
+	}
+	keys := collectValuesKeys(docs)
+
+	result := &InitResult{Subcharts: sortedNames(subchartNames(ctxDir))}
+	var ambiguous []valuesKey
+	for _, k := range keys {
+		if values := enumValues(k); values != nil {
+			result.Enums = append(result.Enums, k.path)
+		}
+		if ambiguousType(k.value) != "" {
+			result.Ambiguous = append(result.Ambiguous, k.path)
+			ambiguous = append(ambiguous, k)
+		}
+	}
+
+	if writeConfig {
+		content := initConfig(chartName(ctxDir), outputPath(".", outputFormat(ctxDir)), result.Subcharts, keys)
+		if err := os.WriteFile(configPath, content, 0644); err != nil {
+			return nil, fmt.Errorf("error writing %s: %w", configPath, err)
+		}
+		result.Config = configPath
+	}
+
+	if annotate {
+		annotated, n := insertPlaceholders(data, ambiguous)
+		if n > 0 {
+			info, err := os.Stat(valuesPath)
+			if err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(valuesPath, annotated, info.Mode().Perm()); err != nil {
+				return nil, fmt.Errorf("error writing %s: %w", valuesPath, err)
+			}
+		}
+		result.Annotated = n
+	}
+	return result, nil
+}
+
+// collectValuesKeys returns the keys of the values documents in file order. A path that
+// occurs more than once (in later documents or sequence items) is returned once.
+func collectValuesKeys(docs []*yaml.Node) []valuesKey {
+	var keys []valuesKey
+	seen := make(map[string]bool)
+	var walk func(node *yaml.Node, path string, inSequence bool)
+	walk = func(node *yaml.Node, path string, inSequence bool) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				childPath := joinValuesPath(path, key.Value)
+				if !seen[childPath] {
+					seen[childPath] = true
+					keys = append(keys, valuesKey{
+						path:        childPath,
+						key:         key,
+						value:       value,
+						annotatable: !inSequence && node.Style&yaml.FlowStyle == 0,
+					})
+				}
+				walk(value, childPath, inSequence)
+			}
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				walk(item, path+"[*]", true)
+			}
+		}
+	}
+	for _, doc := range docs {
+		walk(doc, "", false)
+	}
+	return keys
+}
+
+// enumValues returns the allowed values of a candidate enum key, or nil
+func enumValues(k valuesKey) []string {
+	if k.value.Kind != yaml.ScalarNode || k.value.Tag != "!!str" {
+		return nil
+	}
+	segments := splitValuesPath(k.path)
+	for _, c := range enumCandidates {
+		if !matchValuesPath(c.pattern, segments) {
+			continue
+		}
+		for _, v := range c.values {
+			if v == k.value.Value {
+				return c.values
+			}
+		}
+	}
+	return nil
+}
+
+// ambiguousType returns the JSON Schema type suggested for a value whose type cannot be
+// fully inferred, or "" when the value is not ambiguous
+func ambiguousType(value *yaml.Node) string {
+	switch {
+	case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+		return "string"
+	case value.Kind == yaml.ScalarNode && value.Tag == "!!str" && value.Value == "":
+		return "string"
+	case value.Kind == yaml.MappingNode && len(value.Content) == 0:
+		return "object"
+	case value.Kind == yaml.SequenceNode && len(value.Content) == 0:
+		return "array"
+	}
+	return ""
+}
+
+// placeholderKeyword is the keyword an @schema placeholder asks to fill in for a value
+func placeholderKeyword(value *yaml.Node) string {
+	switch value.Kind {
+	case yaml.MappingNode:
+		return "additionalProperties"
+	case yaml.SequenceNode:
+		return "items"
+	}
+	return "type"
+}
+
+// insertPlaceholders inserts an empty @schema block above each annotatable key that does not
+// have one yet, returning the new data and the number of blocks inserted
+func insertPlaceholders(data []byte, keys []valuesKey) ([]byte, int) {
+	newline := "\n"
+	if bytes.Contains(data, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+	lines := strings.Split(string(data), newline)
+	inserts := make(map[int][]string)
+	n := 0
+	for _, k := range keys {
+		if !k.annotatable || k.key.Line < 1 || k.key.Line > len(lines) {
+			continue
+		}
+		if _, found, _ := annotationBlock(k.key.HeadComment); found {
+			continue
+		}
+		indent := strings.Repeat(" ", k.key.Column-1)
+		inserts[k.key.Line-1] = []string{
+			indent + "# " + annotationMarker,
+			indent + "# " + placeholderKeyword(k.value) + ":",
+			indent + "# " + annotationMarker,
+		}
+		n++
+	}
+	if n == 0 {
+		return data, 0
+	}
+	out := make([]string, 0, len(lines)+3*n)
+	for i, line := range lines {
+		out = append(out, inserts[i]...)
+		out = append(out, line)
+	}
+	return []byte(strings.Join(out, newline)), n
+}
+
+// chartName returns the name in the Chart.yaml of ctxDir, else the directory name
+func chartName(ctxDir string) string {
+	if data, err := os.ReadFile(filepath.Join(ctxDir, "Chart.yaml")); err == nil {
+		var chart struct {
+			Name string `yaml:"name"`
+		}
+		if yaml.Unmarshal(data, &chart) == nil && chart.Name != "" {
+			return chart.Name
+		}
+	}
+	return filepath.Base(absPath(ctxDir))
+}
+
+// sortedNames returns the keys of a set, sorted
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// yamlScalar renders s as a YAML scalar, quoted when needed
+func yamlScalar(s string) string {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf("%q", s)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// initConfig renders the commented .valet.yaml of a chart
+func initConfig(name, output string, subcharts []string, keys []valuesKey) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# yaml-language-server: $schema=%s\n", configSchemaURL)
+	fmt.Fprintf(&b, "# valet configuration of the %s chart, written by valet init.\n", name)
+	b.WriteString("# Check it with valet config validate; every setting is described in\n")
+	b.WriteString("# https://github.com/mkm29/valet#configuration-file\n\n")
+
+	b.WriteString("# Output file of the generated schema; the extension selects the format\n")
+	fmt.Fprintf(&b, "# output: %s\n\n", yamlScalar(output))
+
+	b.WriteString("# Check values.yaml on load for duplicate keys, yes/no booleans and ambiguous\n")
+	b.WriteString("# numbers: \"off\", \"warn\" or \"error\"\n")
+	b.WriteString("# strict: warn\n\n")
+
+	b.WriteString("# JSON Schema draft of the generated $schema\n")
+	b.WriteString("# draft: draft-07\n\n")
+
+	present := make(map[string]bool)
+	for _, k := range keys {
+		present[k.path] = true
+	}
+	var rules, commented strings.Builder
+	for _, sub := range subcharts {
+		if !present[sub] {
+			continue
+		}
+		if rules.Len() == 0 {
+			rules.WriteString("  # Values passed to subcharts, which validate them with their own schemas\n")
+		}
+		fmt.Fprintf(&rules, "  - path: %s\n", yamlScalar(sub))
+		fmt.Fprintf(&rules, "    description: %s\n", yamlScalar("Values of the "+sub+" subchart"))
+	}
+	enumHeader := false
+	for _, k := range keys {
+		values := enumValues(k)
+		if values == nil {
+			continue
+		}
+		if !enumHeader {
+			rules.WriteString("  # Candidate enums, detected from the keys and values of values.yaml\n")
+			enumHeader = true
+		}
+		quoted := make([]string, len(values))
+		for i, v := range values {
+			quoted[i] = yamlScalar(v)
+		}
+		fmt.Fprintf(&rules, "  - path: %s\n", yamlScalar(k.path))
+		fmt.Fprintf(&rules, "    enum: [%s]\n", strings.Join(quoted, ", "))
+	}
+	for _, k := range keys {
+		suggested := ambiguousType(k.value)
+		if suggested == "" {
+			continue
+		}
+		if commented.Len() == 0 {
+			commented.WriteString("  # Values whose type cannot be inferred from values.yaml; set it here or with\n")
+			commented.WriteString("  # an @schema annotation in values.yaml (valet init --annotate)\n")
+		}
+		fmt.Fprintf(&commented, "  # - path: %s\n", yamlScalar(k.path))
+		fmt.Fprintf(&commented, "  #   type: %s\n", suggested)
+	}
+
+	if len(subcharts) > 0 {
+		fmt.Fprintf(&b, "# Subcharts: %s\n", strings.Join(subcharts, ", "))
+	}
+	b.WriteString("# Rules adjusting the inferred schema of the values matching a path glob\n")
+	b.WriteString("# (\"*\" matches one key, \"**\" any number of keys, \"[*]\" array items)\n")
+	if rules.Len() > 0 {
+		b.WriteString("rules:\n")
+		b.WriteString(rules.String())
+	} else {
+		b.WriteString("# rules:\n")
+		b.WriteString("#   - path: \"*.resources\"\n")
+		b.WriteString("#     description: Kubernetes resource requests and limits\n")
+	}
+	b.WriteString(commented.String())
+	return []byte(b.String())
+}
+
+// printInitResult reports what valet init wrote and detected
+func printInitResult(out io.Writer, r *InitResult, annotate bool) {
+	if r.Config != "" {
+		fmt.Fprintf(out, "Wrote %s\n", displayPath(r.Config))
+	} else {
+		fmt.Fprintf(out, "Kept the existing %s\n", config.FileName)
+	}
+	if len(r.Subcharts) > 0 {
+		fmt.Fprintf(out, "Subcharts: %s\n", strings.Join(r.Subcharts, ", "))
+	}
+	if len(r.Enums) > 0 {
+		fmt.Fprintf(out, "Candidate enums: %s\n", strings.Join(r.Enums, ", "))
+	}
+	if len(r.Ambiguous) > 0 {
+		fmt.Fprintf(out, "Values with an ambiguous type: %s\n", strings.Join(r.Ambiguous, ", "))
+		if annotate {
+			fmt.Fprintf(out, "Inserted %d @schema placeholder(s) into the values file\n", r.Annotated)
+		} else {
+			fmt.Fprintln(out, "Run valet init --annotate to insert @schema placeholders for them into the values file")
+		}
+	}
+}
+
+func NewInitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init [chart-dir]",
+		Short: "Write a .valet.yaml tuned to a chart",
+		Long: `Write a commented .valet.yaml for a chart (default: the working directory).
+
+The file is tuned to the chart: the values of its subcharts are described, well-known
+settings such as image.pullPolicy and service.type become enum rules, and the values
+whose type cannot be inferred (empty strings, empty maps and lists, and nulls) are
+listed as rules to fill in.
+
+With --annotate, an empty @schema block is inserted into values.yaml above each of those
+values instead, to be filled in with the JSON Schema keywords of the value:
+
+  # @schema
+  # type: string
+  # @schema
+  tag: ""
+
+An existing .valet.yaml is only overwritten with --force; with --annotate it is kept.`,
+		Args: cobra.MaximumNArgs(1),
+		// Do not print usage on error; just show the error message
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctxDir := "."
+			if len(args) > 0 && args[0] != "" {
+				ctxDir = args[0]
+			}
+			force, err := cmd.Flags().GetBool("force")
+			if err != nil {
+				return err
+			}
+			annotate, err := cmd.Flags().GetBool("annotate")
+			if err != nil {
+				return err
+			}
+			result, err := InitChart(ctxDir, force, annotate)
+			if err != nil {
+				return err
+			}
+			printInitResult(cmd.OutOrStdout(), result, annotate)
+			return nil
+		},
+	}
+	cmd.Flags().Bool("force", false, "overwrite an existing .valet.yaml")
+	cmd.Flags().Bool("annotate", false, "insert @schema placeholders into values.yaml above the values whose type is ambiguous")
+	return cmd
+}
@@ -0,0 +1,356 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// lint subcommand
+
+// Lint severities; info findings are reported but do not fail the lint
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+// Lint report formats
+const (
+	reportText  = "text"
+	reportJSON  = "json"
+	reportSARIF = "sarif"
+)
+
+// lintFinding is a single problem reported by valet lint
+type lintFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+// lintChart runs the lint rules against the values file of the chart in ctxDir
+func lintChart(ctxDir string) ([]lintFinding, error) {
+	valuesPath, err := findValuesFile(ctxDir)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(valuesPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", valuesPath, err)
+	}
+	docs, err := parseDocuments(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", valuesPath, err)
+	}
+
+	var findings []lintFinding
+	for _, doc := range docs {
+		if doc.Kind != yaml.MappingNode {
+			continue
+		}
+		findings = append(findings, checkValues(valuesPath, doc)...)
+		unused, err := lintUnusedValues(ctxDir, valuesPath, doc)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, unused...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+	return findings, nil
+}
+
+// lintUnusedValues reports the keys of values that no template or subchart reads. Only the
+// topmost unused key of a subtree is reported.
+func lintUnusedValues(ctxDir, file string, values *yaml.Node) ([]lintFinding, error) {
+	if info, err := os.Stat(filepath.Join(ctxDir, "templates")); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+	refs, err := scanTemplates(ctxDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Values of subcharts and global values are read outside of this chart's templates
+	external := subchartNames(ctxDir)
+	external["global"] = true
+
+	u := &usage{refs: refs, reported: make(map[string]bool), file: file}
+	for i := 0; i+1 < len(values.Content); i += 2 {
+		key := values.Content[i]
+		if external[key.Value] {
+			continue
+		}
+		u.check(key, values.Content[i+1], []string{key.Value})
+	}
+	return u.findings, nil
+}
+
+// usage checks values paths against the references of the chart templates
+type usage struct {
+	refs     []*templateRef
+	reported map[string]bool
+	findings []lintFinding
+	file     string
+}
+
+// check reports the value at path as unused, or descends into it when only parts of it are used
+func (u *usage) check(key, value *yaml.Node, path []string) {
+	if u.wholeUsed(path) {
+		return
+	}
+	if !u.touched(path) {
+		p := valuesPathString(path)
+		if !u.reported[p] {
+			u.reported[p] = true
+			u.findings = append(u.findings, lintFinding{
+				Rule:     "unused-value",
+				Severity: severityWarning,
+				File:     u.file,
+				Line:     key.Line,
+				Column:   key.Column,
+				Path:     p,
+				Message:  fmt.Sprintf("%s is not used by any template or subchart", p),
+			})
+		}
+		return
+	}
+	switch value.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			child := value.Content[i]
+			u.check(child, value.Content[i+1], appendPath(path, child.Value))
+		}
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			if item.Kind == yaml.MappingNode {
+				u.check(key, item, appendPath(path, "[*]"))
+			}
+		}
+	}
+}
+
+// wholeUsed reports whether the value at path, or one of its parents, is used as a whole
+func (u *usage) wholeUsed(path []string) bool {
+	for _, ref := range u.refs {
+		if ref.Whole && hasPathPrefix(path, ref.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// touched reports whether any template reads the value at path or a value below it
+func (u *usage) touched(path []string) bool {
+	for _, ref := range u.refs {
+		if hasPathPrefix(ref.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPathPrefix reports whether prefix is a prefix of (or equal to) path
+func hasPathPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesPathString formats a values path as dotted path, e.g. ingress.hosts[*].host
+func valuesPathString(path []string) string {
+	var b strings.Builder
+	for i, seg := range path {
+		if i > 0 && seg != "[*]" {
+			b.WriteString(".")
+		}
+		b.WriteString(seg)
+	}
+	return b.String()
+}
+
+// subchartNames returns the names (and aliases) of the chart's dependencies and of the
+// charts vendored in its charts/ directory
+func subchartNames(ctxDir string) map[string]bool {
+	names := make(map[string]bool)
+	if data, err := os.ReadFile(filepath.Join(ctxDir, "Chart.yaml")); err == nil {
+		var chart struct {
+			Dependencies []struct {
+				Name  string `yaml:"name"`
+				Alias string `yaml:"alias"`
+			} `yaml:"dependencies"`
+		}
+		if yaml.Unmarshal(data, &chart) == nil {
+			for _, dep := range chart.Dependencies {
+				names[dep.Name] = true
+				if dep.Alias != "" {
+					names[dep.Alias] = true
+				}
+			}
+		}
+	}
+	if entries, err := os.ReadDir(filepath.Join(ctxDir, "charts")); err == nil {
+		for _, e := range entries {
+			name := e.Name()
+			if !e.IsDir() {
+				// Packaged subcharts are named <name>-<version>.tgz
+				if !strings.HasSuffix(name, ".tgz") {
+					continue
+				}
+				name = strings.TrimSuffix(name, ".tgz")
+				if i := strings.LastIndex(name, "-"); i > 0 {
+					name = name[:i]
+				}
+			}
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// failing counts the findings that make the lint fail
+func failing(findings []lintFinding) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity != severityInfo {
+			n++
+		}
+	}
+	return n
+}
+
+// writeFindings writes findings in the given report format
+func writeFindings(w io.Writer, findings []lintFinding, format string) error {
+	switch format {
+	case reportText:
+		for _, f := range findings {
+			fmt.Fprintf(w, "%s:%d:%d: %s: %s (%s)\n", f.File, f.Line, f.Column, f.Severity, f.Message, f.Rule)
+		}
+		return nil
+	case reportJSON:
+		if findings == nil {
+			findings = []lintFinding{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(findings)
+	case reportSARIF:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sarifReport(findings))
+	}
+	return fmt.Errorf("unsupported report format %q (expected text, json or sarif)", format)
+}
+
+// sarifReport converts findings into a SARIF 2.1.0 log, as consumed by code scanning tools
+func sarifReport(findings []lintFinding) map[string]any {
+	rules := make([]map[string]any, 0, len(lintRules))
+	for _, r := range lintRules {
+		rules = append(rules, map[string]any{
+			"id":               r.ID,
+			"shortDescription": map[string]any{"text": r.Description},
+		})
+	}
+	results := make([]map[string]any, 0, len(findings))
+	for _, f := range findings {
+		level := f.Severity
+		if level == severityInfo {
+			level = "note"
+		}
+		results = append(results, map[string]any{
+			"ruleId":  f.Rule,
+			"level":   level,
+			"message": map[string]any{"text": f.Message},
+			"locations": []map[string]any{{
+				"physicalLocation": map[string]any{
+					"artifactLocation": map[string]any{"uri": filepath.ToSlash(f.File)},
+					"region":           map[string]any{"startLine": f.Line, "startColumn": f.Column},
+				},
+			}},
+		})
+	}
+	return map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []map[string]any{{
+			"tool": map[string]any{
+				"driver": map[string]any{
+					"name":           "valet",
+					"version":        GetBuildVersion(),
+					"informationUri": "https://github.com/mkm29/valet",
+					"rules":          rules,
+				},
+			},
+			"results": results,
+		}},
+	}
+}
+
+func NewLintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint <context-dir>",
+		Short: "Check values.yaml for problems",
+		Long: `Check the values.yaml of a chart for problems.
+
+Rules:
+  unused-value       values not used by any template or subchart
+  duplicate-key      keys defined twice in the same mapping (the last one silently wins)
+  mixed-types        list elements of different types
+  null-string        the string "null" where null was probably meant
+  boolean-string     booleans written as quoted strings ("true")
+  ambiguous-number   plain scalars parsed as floats or octal numbers (1.10, 0755)
+  undocumented-key   keys without a documenting comment (info)
+
+Template references are found by parsing the chart templates, following with/range
+scoping and included helpers; a value passed as a whole (for example to toYaml) counts
+as using everything below it. Findings are printed as text, JSON or SARIF; the command
+fails when there are error or warning findings.`,
+		Args: cobra.ExactArgs(1),
+		// Do not print usage on error; just show the error message
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := cmd.Flags().GetString("report-format")
+			if err != nil {
+				return err
+			}
+			if format != reportText && format != reportJSON && format != reportSARIF {
+				return fmt.Errorf("unsupported report format %q (expected text, json or sarif)", format)
+			}
+			findings, err := lintChart(args[0])
+			if err != nil {
+				return err
+			}
+			if err := writeFindings(cmd.OutOrStdout(), findings, format); err != nil {
+				return err
+			}
+			if n := failing(findings); n > 0 {
+				return fmt.Errorf("found %d problem(s)", n)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String("report-format", reportText, "report format (text, json, sarif)")
+	return cmd
+}
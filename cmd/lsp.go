@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mkm29/valet/internal/lsp"
+	"github.com/spf13/cobra"
+)
+
+// lsp subcommand
+
+// findChartDir returns the chart directory owning dir: the nearest directory at or above dir
+// that contains a Chart.yaml, or dir itself if it holds a values file. It returns "" otherwise.
+func findChartDir(dir string) string {
+	for d := absPath(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "Chart.yaml")); err == nil {
+			return d
+		}
+		if parent := filepath.Dir(d); parent == d {
+			break
+		}
+	}
+	if _, err := findValuesFile(dir); err == nil {
+		return dir
+	}
+	return ""
+}
+
+// lspSchemaFor infers the schema of the chart owning the values document at path
+func lspSchemaFor(path string) (map[string]any, error) {
+	dir := findChartDir(filepath.Dir(path))
+	if dir == "" {
+		return nil, nil
+	}
+	return buildSchema(context.Background(), GetTelemetry(), dir, "")
+}
+
+func NewLSPCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "Start a language server for values files",
+		Long: `Start a Language Server Protocol server on stdio for values.yaml and override files.
+
+The server offers completion of keys and enum values, hover with type, default and
+description, and diagnostics for values that violate the schema valet infers for the chart.`,
+		Args: cobra.NoArgs,
+		// Do not print usage on error; just show the error message
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// stdout carries the protocol, so report the metrics endpoint on stderr
+			if addr, err := GetTelemetry().ServeMetrics(); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+			} else if addr != "" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Serving metrics on http://%s/metrics\n", addr)
+			}
+			server := lsp.NewServer(cmd.InOrStdin(), cmd.OutOrStdout(), lspSchemaFor, GetBuildVersion())
+			return server.Run(cmd.Context())
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// schema overlay support
+
+// defaultOverlayFile is the sidecar overlay looked up next to values.yaml
+const defaultOverlayFile = "values.schema.overlay.yaml"
+
+// resolveOverlayPath returns the overlay file to apply for ctxDir.
+// The second return value reports whether the path was set explicitly in the config,
+// in which case a missing file is an error rather than silently skipped.
+func resolveOverlayPath(ctxDir string) (string, bool) {
+	if c := configFor(ctxDir); c != nil && c.SchemaOverlay != "" {
+		if filepath.IsAbs(c.SchemaOverlay) {
+			return c.SchemaOverlay, true
+		}
+		return filepath.Join(ctxDir, c.SchemaOverlay), true
+	}
+	return filepath.Join(ctxDir, defaultOverlayFile), false
+}
+
+// loadOverlay reads the overlay file for ctxDir. It returns a nil map when no overlay applies.
+func loadOverlay(ctxDir string) (map[string]any, string, error) {
+	path, explicit := resolveOverlayPath(ctxDir)
+	if _, err := os.Stat(path); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, path, err
+		}
+		if !explicit {
+			return nil, "", nil
+		}
+		return nil, path, fmt.Errorf("schema overlay %s not found", path)
+	}
+	overlay, err := loadYAML(path, strictMode(ctxDir))
+	if err != nil {
+		return nil, path, err
+	}
+	return overlay, path, nil
+}
+
+// applyOverlay deep-merges each fragment in overlay onto the schema node addressed by
+// its dotted values path (e.g. "image.tag" or "ingress.hosts[*].host"). The key "."
+// addresses the root schema. Nodes that do not exist yet are created.
+func applyOverlay(schema map[string]any, overlay map[string]any) error {
+	// Apply in a stable order so that overlapping paths merge deterministically
+	paths := make([]string, 0, len(overlay))
+	for p := range overlay {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		fragment, ok := overlay[p].(map[string]any)
+		if !ok {
+			return fmt.Errorf("overlay entry %q must be a mapping, got %T", p, overlay[p])
+		}
+		target := schemaNodeAt(schema, splitValuesPath(p))
+		for k, v := range deepMerge(target, fragment) {
+			target[k] = v
+		}
+	}
+	return nil
+}
+
+// splitValuesPath splits a dotted values path into segments. Array items are addressed
+// with a "[*]" suffix, which becomes its own segment. A backslash escapes a literal dot.
+func splitValuesPath(path string) []string {
+	if path == "" || path == "." {
+		return nil
+	}
+	var segments []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			segments = append(segments, cur.String())
+			cur.Reset()
+		}
+	}
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path):
+			i++
+			cur.WriteByte(path[i])
+		case path[i] == '.':
+			flush()
+		case strings.HasPrefix(path[i:], "[*]"):
+			flush()
+			segments = append(segments, "[*]")
+			i += 2
+		default:
+			cur.WriteByte(path[i])
+		}
+	}
+	flush()
+	return segments
+}
+
+// schemaNodeAt walks schema along segments, creating empty nodes as needed,
+// and returns the node at the end of the path.
+func schemaNodeAt(schema map[string]any, segments []string) map[string]any {
+	node := schema
+	for _, seg := range segments {
+		if seg == "[*]" {
+			items, ok := node["items"].(map[string]any)
+			if !ok {
+				items = map[string]any{}
+				node["items"] = items
+			}
+			node = items
+			continue
+		}
+		props, ok := node["properties"].(map[string]any)
+		if !ok {
+			props = map[string]any{}
+			node["properties"] = props
+		}
+		child, ok := props[seg].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			props[seg] = child
+		}
+		node = child
+	}
+	return node
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/mkm29/valet/internal/config"
@@ -16,6 +17,29 @@ var (
 	tel *telemetry.Telemetry
 )
 
+func init() {
+	// Tear down after every execution, including failed ones
+	cobra.OnFinalize(finalize)
+}
+
+// finalize shuts down telemetry and clears the configuration, which is scoped to a
+// single execution
+func finalize() {
+	// Shutdown telemetry if it was initialized
+	if tel != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := tel.Shutdown(shutdownCtx); err != nil {
+			zap.L().Error("Error shutting down telemetry", zap.Error(err))
+			// Don't return error - telemetry shutdown failure shouldn't fail the command
+		}
+		tel = nil
+	}
+	cfg = nil
+	loadChartConfig = nil
+}
+
 func NewRootCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "valet",
@@ -24,14 +48,18 @@ func NewRootCmd() *cobra.Command {
 		// Do not print usage on error; just show the error message
 		SilenceUsage: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			if cfg == nil {
-				c, err := initializeConfig(cmd)
-				if err != nil {
-					return err
-				}
-				cfg = c
+			c, err := initializeConfig(cmd.Root(), configDir(cmd, args))
+			if err != nil {
+				return err
+			}
+			cfg = c
+			loadChartConfig = func(dir string) (*config.Config, error) {
+				return initializeConfig(cmd.Root(), dir)
 			}
 
+			// Join the trace of the pipeline that started valet, if any
+			cmd.SetContext(telemetry.ContextFromEnv(cmd.Context()))
+
 			// Initialize telemetry if not already initialized
 			if tel == nil && cfg.Telemetry != nil {
 				ctx := cmd.Context()
@@ -48,19 +76,6 @@ func NewRootCmd() *cobra.Command {
 
 			return nil
 		},
-		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
-			// Shutdown telemetry if it was initialized
-			if tel != nil {
-				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-
-				if err := tel.Shutdown(shutdownCtx); err != nil {
-					zap.L().Error("Error shutting down telemetry", zap.Error(err))
-					// Don't return error - telemetry shutdown failure shouldn't fail the command
-				}
-			}
-			return nil
-		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Default action: delegate to Generate
 			ctx := cfg.Context
@@ -80,97 +95,140 @@ func NewRootCmd() *cobra.Command {
 	}
 
 	// Support CLI flags for configuration (config file, context, overrides, output, debug)
-	// Config file path (default: .valet.yaml)
-	cmd.PersistentFlags().String("config-file", ".valet.yaml", "config file path (default: .valet.yaml)")
+	// Config file path (default: discovered .valet.yaml files)
+	cmd.PersistentFlags().String("config-file", "", "config file to read instead of discovering .valet.yaml files")
 	cmd.PersistentFlags().StringP("context", "c", ".", "context directory containing values.yaml (optional)")
+	cmd.PersistentFlags().String("values", "", "values file relative to the context directory (default: values.yaml, values.yml or values.json)")
 	cmd.PersistentFlags().StringP("overrides", "f", "", "overrides file (optional)")
-	cmd.PersistentFlags().StringP("output", "o", "values.schema.json", "output file (default: values.schema.json)")
+	cmd.PersistentFlags().StringP("output", "o", "", "output file (default: values.schema.json, values.schema.yaml, values.ts or values.go depending on --format)")
+	cmd.PersistentFlags().String("format", "", "output format (json, yaml, typescript, go; default: inferred from --output, else json)")
+	cmd.PersistentFlags().String("strict", "", "check values files on load for duplicate keys, yes/no booleans and ambiguous numbers (off, warn, error)")
+	cmd.PersistentFlags().Bool("cache", false, "skip generating schemas whose inputs are unchanged (tracked in the user cache directory)")
 	cmd.PersistentFlags().BoolP("debug", "d", false, "enable debug logging")
 
 	// Telemetry flags
 	cmd.PersistentFlags().Bool("telemetry-enabled", false, "enable telemetry")
-	cmd.PersistentFlags().String("telemetry-exporter", "none", "telemetry exporter type (none, stdout, otlp)")
-	cmd.PersistentFlags().String("telemetry-endpoint", "localhost:4317", "OTLP endpoint for telemetry")
+	cmd.PersistentFlags().String("telemetry-exporter", "none", "telemetry exporter type (none, stdout, otlp, otlphttp, file)")
+	cmd.PersistentFlags().String("telemetry-endpoint", "", "OTLP endpoint for telemetry (default: localhost:4317, or localhost:4318 for otlphttp)")
 	cmd.PersistentFlags().Bool("telemetry-insecure", false, "use insecure connection for OTLP")
 	cmd.PersistentFlags().Float64("telemetry-sample-rate", 1.0, "trace sampling rate (0.0 to 1.0)")
 
 	// add subcommands
 	cmd.AddCommand(NewVersionCmd())
 	cmd.AddCommand(NewGenerateCmd())
+	cmd.AddCommand(NewLSPCmd())
+	cmd.AddCommand(NewLintCmd())
+	cmd.AddCommand(NewHookCmd())
+	cmd.AddCommand(NewConfigCmd())
+	cmd.AddCommand(NewInitCmd())
 
 	return cmd
 }
 
-// initializeConfig loads configuration from file and applies CLI flags
-func initializeConfig(cmd *cobra.Command) (*config.Config, error) {
-	// Only read config file if flag explicitly set
-	var c *config.Config
-	var err error
+// configDir returns the directory config files are discovered from: the directory given
+// as first argument (e.g. the chart of generate), else --context, else the working directory
+func configDir(cmd *cobra.Command, args []string) string {
+	if len(args) > 0 && args[0] != "-" {
+		if info, err := os.Stat(args[0]); err == nil && info.IsDir() {
+			return args[0]
+		}
+	}
+	if f := cmd.Root().PersistentFlags().Lookup("context"); f != nil && f.Changed {
+		return f.Value.String()
+	}
+	return "."
+}
+
+// initializeConfig loads the configuration for dir and applies environment variables and
+// CLI flags. An explicit --config-file is read on its own; otherwise the config files
+// discovered for dir are merged, nearer files overriding farther ones.
+func initializeConfig(cmd *cobra.Command, dir string) (*config.Config, error) {
+	var files []string
 	if cmd.PersistentFlags().Changed("config-file") {
 		cfgFile, _ := cmd.PersistentFlags().GetString("config-file")
-		c, err = config.LoadConfig(cfgFile)
-		if err != nil {
-			return nil, err
-		}
+		files = []string{cfgFile}
 	} else {
-		// No config file: start with empty config
-		c = &config.Config{
-			Telemetry: config.NewTelemetryConfig(),
-		}
+		files = config.DiscoverFiles(dir)
+	}
+	c, err := config.LoadFiles(files...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Environment variables override the config file; flags override both
+	if err := c.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
 	}
 
 	// Always set the service version from build info, regardless of config source
 	if c.Telemetry != nil {
 		c.Telemetry.ServiceVersion = GetBuildVersion()
+		c.SetOrigin("telemetry.serviceVersion", "build info")
+	}
+	flags := cmd.PersistentFlags()
+	// changed reports whether a flag was set, recording it as the origin of the setting key
+	changed := func(flag, key string) bool {
+		if !flags.Changed(flag) {
+			return false
+		}
+		c.SetOrigin(key, "flag --"+flag)
+		return true
 	}
+
 	// Override with CLI flags or defaults
 	// Context: default to value or override
-	// Context flag override
-	cliCtx, _ := cmd.PersistentFlags().GetString("context")
-	if cmd.PersistentFlags().Changed("context") || c.Context == "" {
+	cliCtx, _ := flags.GetString("context")
+	if changed("context", "context") || c.Context == "" {
 		c.Context = cliCtx
 	}
-	if cmd.PersistentFlags().Changed("overrides") {
-		ov, _ := cmd.PersistentFlags().GetString("overrides")
-		c.Overrides = ov
+	if changed("values", "values") {
+		c.Values, _ = flags.GetString("values")
+	}
+	if changed("overrides", "overrides") {
+		c.Overrides, _ = flags.GetString("overrides")
+	}
+	if changed("output", "output") {
+		c.Output, _ = flags.GetString("output")
+	}
+	if changed("format", "format") {
+		c.Format, _ = flags.GetString("format")
 	}
-	if cmd.PersistentFlags().Changed("output") {
-		out, _ := cmd.PersistentFlags().GetString("output")
-		c.Output = out
+	if changed("strict", "strict") {
+		c.Strict, _ = flags.GetString("strict")
 	}
-	if cmd.PersistentFlags().Changed("debug") {
-		dbg, _ := cmd.PersistentFlags().GetBool("debug")
-		c.Debug = dbg
+	if changed("cache", "cache") {
+		c.Cache, _ = flags.GetBool("cache")
+	}
+	if changed("debug", "debug") {
+		c.Debug, _ = flags.GetBool("debug")
 	}
 
 	// Handle telemetry flags
 	if c.Telemetry == nil {
 		c.Telemetry = config.NewTelemetryConfig()
 	}
-
-	if cmd.PersistentFlags().Changed("telemetry-enabled") {
-		enabled, _ := cmd.PersistentFlags().GetBool("telemetry-enabled")
-		c.Telemetry.Enabled = enabled
+	if changed("telemetry-enabled", "telemetry.enabled") {
+		c.Telemetry.Enabled, _ = flags.GetBool("telemetry-enabled")
 	}
-	if cmd.PersistentFlags().Changed("telemetry-exporter") {
-		exporter, _ := cmd.PersistentFlags().GetString("telemetry-exporter")
-		c.Telemetry.ExporterType = exporter
+	if changed("telemetry-exporter", "telemetry.exporterType") {
+		c.Telemetry.ExporterType, _ = flags.GetString("telemetry-exporter")
 	}
-	if cmd.PersistentFlags().Changed("telemetry-endpoint") {
-		endpoint, _ := cmd.PersistentFlags().GetString("telemetry-endpoint")
-		c.Telemetry.OTLPEndpoint = endpoint
+	if changed("telemetry-endpoint", "telemetry.otlpEndpoint") {
+		c.Telemetry.OTLPEndpoint, _ = flags.GetString("telemetry-endpoint")
 	}
-	if cmd.PersistentFlags().Changed("telemetry-insecure") {
-		insecure, _ := cmd.PersistentFlags().GetBool("telemetry-insecure")
-		c.Telemetry.Insecure = insecure
+	if changed("telemetry-insecure", "telemetry.insecure") {
+		c.Telemetry.Insecure, _ = flags.GetBool("telemetry-insecure")
 	}
-	if cmd.PersistentFlags().Changed("telemetry-sample-rate") {
-		rate, _ := cmd.PersistentFlags().GetFloat64("telemetry-sample-rate")
-		c.Telemetry.SampleRate = rate
+	if changed("telemetry-sample-rate", "telemetry.sampleRate") {
+		c.Telemetry.SampleRate, _ = flags.GetFloat64("telemetry-sample-rate")
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
 	}
 
 	if c.Debug {
-		zap.L().Debug("Config loaded", zap.Any("config", c))
+		zap.L().Debug("Config loaded", zap.Strings("files", files), zap.Any("config", c))
 	}
 	return c, nil
 }
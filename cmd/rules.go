@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/mkm29/valet/internal/config"
+	"go.uber.org/zap"
+)
+
+// per-chart settings and path-scoped schema rules from the config files
+
+// draftSchemas maps the supported JSON Schema drafts to their $schema URI
+var draftSchemas = map[string]string{
+	"draft-04": "http://json-schema.org/draft-04/schema#",
+	"draft-06": "http://json-schema.org/draft-06/schema#",
+	"draft-07": "http://json-schema.org/draft-07/schema#",
+	"2019-09":  "https://json-schema.org/draft/2019-09/schema",
+	"2020-12":  "https://json-schema.org/draft/2020-12/schema",
+}
+
+// defaultSchemaURI is the $schema of generated schemas when no draft is configured
+const defaultSchemaURI = "http://json-schema.org/schema#"
+
+// chartConfigs holds the configuration of every chart of a --recursive run, keyed by
+// absolute chart directory, as each chart reads the config files discovered for it. It is
+// filled before the charts are generated and only read while they are.
+var chartConfigs map[string]*config.Config
+
+// configFor returns the configuration that applies to the chart in ctxDir
+func configFor(ctxDir string) *config.Config {
+	if ctxDir != "" {
+		if c, ok := chartConfigs[absPath(ctxDir)]; ok {
+			return c
+		}
+	}
+	return cfg
+}
+
+// chartConfig returns the charts entry of the config for ctxDir, or nil
+func chartConfig(ctxDir string) *config.ChartConfig {
+	c := configFor(ctxDir)
+	if c == nil || ctxDir == "" {
+		return nil
+	}
+	dir := absPath(ctxDir)
+	for i := range c.Charts {
+		if c.Charts[i].Context != "" && absPath(c.Charts[i].Context) == dir {
+			return &c.Charts[i]
+		}
+	}
+	return nil
+}
+
+// chartSetting returns the chart's value for the top-level setting key of c, unless the
+// chart does not set it or the setting was given by an environment variable or flag
+func chartSetting(c *config.Config, key, chartValue, topValue string) string {
+	if chartValue == "" {
+		return topValue
+	}
+	if origin := c.Origin(key); strings.HasPrefix(origin, "env ") || strings.HasPrefix(origin, "flag ") {
+		return topValue
+	}
+	return chartValue
+}
+
+// configuredOutput returns the output file configured for ctxDir, or ""
+func configuredOutput(ctxDir string) string {
+	c := configFor(ctxDir)
+	if c == nil {
+		return ""
+	}
+	if chart := chartConfig(ctxDir); chart != nil {
+		return chartSetting(c, "output", chart.Output, c.Output)
+	}
+	return c.Output
+}
+
+// configuredOverrides returns the overrides file configured for ctxDir in the charts section, or ""
+func configuredOverrides(ctxDir string) string {
+	if chart := chartConfig(ctxDir); chart != nil {
+		return chart.Overrides
+	}
+	return ""
+}
+
+// schemaURI returns the $schema of the schema generated for ctxDir
+func schemaURI(ctxDir string) string {
+	c := configFor(ctxDir)
+	if c == nil {
+		return defaultSchemaURI
+	}
+	draft := c.Draft
+	if chart := chartConfig(ctxDir); chart != nil {
+		draft = chartSetting(c, "draft", chart.Draft, c.Draft)
+	}
+	if uri, ok := draftSchemas[draft]; ok {
+		return uri
+	}
+	return defaultSchemaURI
+}
+
+// schemaRules returns the rules that apply to ctxDir: the top-level rules, then the chart's
+func schemaRules(ctxDir string) []config.Rule {
+	c := configFor(ctxDir)
+	if c == nil {
+		return nil
+	}
+	rules := c.Rules
+	if chart := chartConfig(ctxDir); chart != nil && len(chart.Rules) > 0 {
+		rules = append(append([]config.Rule{}, rules...), chart.Rules...)
+	}
+	return rules
+}
+
+// applyRules applies the rules, in order, to every property of schema whose values path
+// matches. It returns the number of properties a rule applied to.
+func applyRules(schema map[string]any, rules []config.Rule) int {
+	applied := 0
+	for _, rule := range rules {
+		pattern := splitValuesPath(rule.Path)
+		if len(pattern) == 0 {
+			continue
+		}
+		n := applyRule(schema, nil, pattern, rule)
+		if n == 0 {
+			zap.L().Debug("Schema rule matched no values", zap.String("path", rule.Path))
+		}
+		applied += n
+	}
+	return applied
+}
+
+// applyRule applies rule to the properties below node, at values path segments
+func applyRule(node map[string]any, segments, pattern []string, rule config.Rule) int {
+	applied := 0
+	if props, ok := node["properties"].(map[string]any); ok {
+		keys := make([]string, 0, len(props))
+		for k := range props {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child, ok := props[k].(map[string]any)
+			if !ok {
+				continue
+			}
+			childPath := append(append([]string{}, segments...), k)
+			if matchValuesPath(pattern, childPath) {
+				applied++
+				if rule.Ignore {
+					delete(props, k)
+					setRequired(node, k, false)
+					continue
+				}
+				applyRuleTo(child, rule)
+				if rule.Required != nil {
+					setRequired(node, k, *rule.Required)
+				}
+			}
+			applied += applyRule(child, childPath, pattern, rule)
+		}
+	}
+	if items, ok := node["items"].(map[string]any); ok {
+		applied += applyRule(items, append(append([]string{}, segments...), "[*]"), pattern, rule)
+	}
+	return applied
+}
+
+// applyRuleTo sets the keywords of rule on a property schema
+func applyRuleTo(prop map[string]any, rule config.Rule) {
+	if rule.Type != "" {
+		prop["type"] = rule.Type
+	}
+	if rule.Format != "" {
+		prop["format"] = rule.Format
+	}
+	if len(rule.Enum) > 0 {
+		prop["enum"] = convertToStringKeyMap(append([]any{}, rule.Enum...))
+	}
+	if rule.Description != "" {
+		prop["description"] = rule.Description
+	}
+}
+
+// setRequired adds key to, or removes it from, the required properties of an object schema
+func setRequired(node map[string]any, key string, required bool) {
+	set := requiredSet(node)
+	if set[key] == required {
+		return
+	}
+	if required {
+		set[key] = true
+	} else {
+		delete(set, key)
+	}
+	if len(set) == 0 {
+		delete(node, "required")
+		return
+	}
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	node["required"] = keys
+}
+
+// matchValuesPath reports whether a values path matches a pattern. Pattern segments are
+// matched with path.Match, so "*" matches any single key; "**" matches any number of
+// segments, including none. The "[*]" segment for array items matches literally.
+func matchValuesPath(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchValuesPath(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if pattern[0] == "[*]" || segments[0] == "[*]" {
+		if pattern[0] != segments[0] {
+			return false
+		}
+	} else if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+		return false
+	}
+	return matchValuesPath(pattern[1:], segments[1:])
+}
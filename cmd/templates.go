@@ -0,0 +1,406 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"go.uber.org/zap"
+)
+
+// Chart template scanning for .Values references
+
+// templateRef is a values path read by a chart template, with the type implied by a
+// `default` pipeline, if any
+type templateRef struct {
+	Path []string
+	Type string
+	// Whole is set when the value itself is used (printed, passed to a function such as
+	// toYaml, tested by if), rather than only entered by with or range
+	Whole bool
+}
+
+// dotScope describes what dot (or a variable) refers to while walking a template
+type dotScope struct {
+	root   bool     // the top-level template context, where .Values is available
+	values bool     // a values path
+	path   []string // the values path when values is set
+}
+
+// templateScanner collects .Values references from a set of parsed templates
+type templateScanner struct {
+	trees   map[string]*parse.Tree
+	refs    map[string]*templateRef
+	visited map[string]bool
+}
+
+// scanTemplates parses the chart templates in ctxDir/templates and returns every values
+// path they reference. Templates that fail to parse are skipped.
+func scanTemplates(ctxDir string) ([]*templateRef, error) {
+	dir := filepath.Join(ctxDir, "templates")
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+
+	s := &templateScanner{
+		trees:   make(map[string]*parse.Tree),
+		refs:    make(map[string]*templateRef),
+		visited: make(map[string]bool),
+	}
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml", ".tpl", ".txt":
+			if !d.IsDir() {
+				files = append(files, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading templates in %s: %w", dir, err)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading template %s: %w", file, err)
+		}
+		t := parse.New(file)
+		t.Mode = parse.SkipFuncCheck | parse.ParseComments
+		treeSet := make(map[string]*parse.Tree)
+		if _, err := t.Parse(string(data), "", "", treeSet); err != nil {
+			zap.L().Debug("Skipping template that failed to parse", zap.String("file", file), zap.Error(err))
+			continue
+		}
+		for name, tree := range treeSet {
+			s.trees[name] = tree
+		}
+	}
+
+	// Every template and define is walked with the chart context as dot; defines that are
+	// included with a values path as argument are walked again from the call site.
+	names := make([]string, 0, len(s.trees))
+	for name := range s.trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.walkTree(name, dotScope{root: true})
+	}
+
+	keys := make([]string, 0, len(s.refs))
+	for k := range s.refs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	refs := make([]*templateRef, 0, len(keys))
+	for _, k := range keys {
+		refs = append(refs, s.refs[k])
+	}
+	return refs, nil
+}
+
+// walkTree walks a named template once per scope
+func (s *templateScanner) walkTree(name string, dot dotScope) {
+	tree, ok := s.trees[name]
+	if !ok || tree.Root == nil {
+		return
+	}
+	key := name + "\x00" + fmt.Sprint(dot)
+	if s.visited[key] {
+		return
+	}
+	s.visited[key] = true
+	s.walk(tree.Root, dot, map[string]dotScope{"$": dot})
+}
+
+// walk collects references below node
+func (s *templateScanner) walk(node parse.Node, dot dotScope, vars map[string]dotScope) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			s.walk(child, dot, vars)
+		}
+	case *parse.ActionNode:
+		s.pipe(n.Pipe, dot, vars)
+	case *parse.IfNode:
+		s.pipe(n.Pipe, dot, vars)
+		s.walk(n.List, dot, copyVars(vars))
+		s.walk(n.ElseList, dot, copyVars(vars))
+	case *parse.WithNode:
+		// Inside with, dot (and the declared variable, if any) is the value of the pipeline
+		scoped := copyVars(vars)
+		inner := s.scopePipe(n.Pipe, dot, scoped)
+		s.walk(n.List, inner, scoped)
+		s.walk(n.ElseList, dot, copyVars(vars))
+	case *parse.RangeNode:
+		// Inside range, dot (and the last declared variable) is an element of the collection
+		scoped := copyVars(vars)
+		src := s.scopePipe(n.Pipe, dot, scoped)
+		item := dotScope{}
+		if src.values {
+			item = dotScope{values: true, path: appendPath(src.path, "[*]")}
+		}
+		for _, decl := range n.Pipe.Decl {
+			scoped[decl.Ident[0]] = dotScope{}
+		}
+		if len(n.Pipe.Decl) > 0 {
+			scoped[n.Pipe.Decl[len(n.Pipe.Decl)-1].Ident[0]] = item
+		}
+		s.walk(n.List, item, scoped)
+		s.walk(n.ElseList, dot, copyVars(vars))
+	case *parse.TemplateNode:
+		if n.Pipe != nil {
+			if arg := s.scopePipe(n.Pipe, dot, vars); arg.values {
+				s.walkTree(n.Name, arg)
+			}
+		}
+	}
+}
+
+// scopePipe handles the pipeline of with, range and template. A pipeline that is a single
+// values path only enters that value; any other pipeline is handled by pipe.
+func (s *templateScanner) scopePipe(p *parse.PipeNode, dot dotScope, vars map[string]dotScope) dotScope {
+	if len(p.Cmds) != 1 || len(p.Cmds[0].Args) != 1 {
+		result, _ := s.pipe(p, dot, vars)
+		return result
+	}
+	result, _ := s.resolve(p.Cmds[0].Args[0], dot, vars)
+	if result.values && len(result.path) > 0 {
+		s.record(result.path)
+	}
+	if len(p.Decl) > 0 {
+		vars[p.Decl[0].Ident[0]] = result
+	}
+	return result
+}
+
+// pipe records the references of a pipeline and returns what the pipeline evaluates to,
+// when that is the chart context or a values path
+func (s *templateScanner) pipe(p *parse.PipeNode, dot dotScope, vars map[string]dotScope) (dotScope, bool) {
+	if p == nil {
+		return dotScope{}, false
+	}
+	result := dotScope{}
+	for i, cmd := range p.Cmds {
+		for j, arg := range cmd.Args {
+			// The argument of include is followed into the helper instead
+			if j == 2 && isInclude(cmd) {
+				if scope, _ := s.resolve(arg, dot, vars); scope.values {
+					continue
+				}
+			}
+			s.arg(arg, dot, vars)
+		}
+
+		// A single operand passes through, anything else yields an unknown value
+		if len(cmd.Args) == 1 {
+			result, _ = s.resolve(cmd.Args[0], dot, vars)
+		} else {
+			result = dotScope{}
+		}
+
+		// `default LITERAL VALUE` and `VALUE | default LITERAL` imply the type of VALUE
+		if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok && len(cmd.Args) >= 2 {
+			switch ident.Ident {
+			case "default":
+				var target dotScope
+				switch {
+				case len(cmd.Args) >= 3:
+					target, _ = s.resolve(cmd.Args[2], dot, vars)
+				case i > 0 && len(p.Cmds[i-1].Args) == 1:
+					target, _ = s.resolve(p.Cmds[i-1].Args[0], dot, vars)
+				}
+				if target.values {
+					s.setType(target.path, literalType(cmd.Args[1]))
+				}
+			case "include", "template":
+				name, isName := cmd.Args[1].(*parse.StringNode)
+				if isName && len(cmd.Args) >= 3 {
+					if arg, _ := s.resolve(cmd.Args[2], dot, vars); arg.values {
+						s.walkTree(name.Text, arg)
+					}
+				}
+			}
+		}
+	}
+	if len(p.Decl) > 0 {
+		vars[p.Decl[0].Ident[0]] = result
+	}
+	return result, result.root || result.values
+}
+
+// isInclude reports whether cmd calls a named template: include "name" arg
+func isInclude(cmd *parse.CommandNode) bool {
+	if len(cmd.Args) < 3 {
+		return false
+	}
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	if !ok || (ident.Ident != "include" && ident.Ident != "template") {
+		return false
+	}
+	_, ok = cmd.Args[1].(*parse.StringNode)
+	return ok
+}
+
+// arg records the references of a single command argument
+func (s *templateScanner) arg(node parse.Node, dot dotScope, vars map[string]dotScope) {
+	switch n := node.(type) {
+	case *parse.PipeNode:
+		s.pipe(n, dot, vars)
+	case *parse.DotNode, *parse.FieldNode, *parse.VariableNode:
+		if scope, ok := s.resolve(n, dot, vars); ok && scope.values && len(scope.path) > 0 {
+			s.record(scope.path).Whole = true
+		}
+	case *parse.ChainNode:
+		s.arg(n.Node, dot, vars)
+	}
+}
+
+// resolve returns what a field, variable or dot node refers to
+func (s *templateScanner) resolve(node parse.Node, dot dotScope, vars map[string]dotScope) (dotScope, bool) {
+	var base dotScope
+	var fields []string
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot, dot.root || dot.values
+	case *parse.FieldNode:
+		base, fields = dot, n.Ident
+	case *parse.VariableNode:
+		v, ok := vars[n.Ident[0]]
+		if !ok {
+			return dotScope{}, false
+		}
+		base, fields = v, n.Ident[1:]
+	case *parse.PipeNode:
+		return s.pipe(n, dot, vars)
+	default:
+		return dotScope{}, false
+	}
+
+	switch {
+	case len(fields) == 0:
+		return base, base.root || base.values
+	case base.values:
+		return dotScope{values: true, path: appendPath(base.path, fields...)}, true
+	case base.root && fields[0] == "Values":
+		return dotScope{values: true, path: appendPath(nil, fields[1:]...)}, true
+	}
+	return dotScope{}, false
+}
+
+// record adds a referenced values path
+func (s *templateScanner) record(path []string) *templateRef {
+	key := strings.Join(path, "\x00")
+	if ref, ok := s.refs[key]; ok {
+		return ref
+	}
+	ref := &templateRef{Path: path}
+	s.refs[key] = ref
+	return ref
+}
+
+// setType records the type implied for a values path
+func (s *templateScanner) setType(path []string, typ string) {
+	if len(path) == 0 || typ == "" {
+		return
+	}
+	ref := s.record(path)
+	if ref.Type == "" {
+		ref.Type = typ
+	}
+}
+
+// literalType returns the JSON Schema type of a template literal
+func literalType(node parse.Node) string {
+	switch n := node.(type) {
+	case *parse.NumberNode:
+		if n.IsInt {
+			return "integer"
+		}
+		return "number"
+	case *parse.StringNode:
+		return "string"
+	case *parse.BoolNode:
+		return "boolean"
+	}
+	return ""
+}
+
+// copyVars copies variables so that declarations inside a block do not leak out of it
+func copyVars(vars map[string]dotScope) map[string]dotScope {
+	out := make(map[string]dotScope, len(vars))
+	for k, v := range vars {
+		out[k] = v
+	}
+	return out
+}
+
+// appendPath returns a new path with the given segments appended
+func appendPath(path []string, segments ...string) []string {
+	out := make([]string, 0, len(path)+len(segments))
+	out = append(out, path...)
+	return append(out, segments...)
+}
+
+// addTemplateRefs adds the template references that values.yaml does not declare to the
+// schema as optional properties, returning the number of properties added
+func addTemplateRefs(schema map[string]any, refs []*templateRef) int {
+	added := 0
+	for _, ref := range refs {
+		node := schema
+		for i, seg := range ref.Path {
+			if t := typeList(node); len(t) > 0 && !hasType(node, "object") && !hasType(node, "array") {
+				// The values file declares a scalar here; keep it
+				break
+			}
+			var child map[string]any
+			if seg == "[*]" {
+				// range works on maps as well as lists, so only declared lists are followed
+				if !hasType(node, "array") {
+					break
+				}
+				child, _ = node["items"].(map[string]any)
+				if child == nil {
+					child = map[string]any{}
+					node["items"] = child
+				}
+			} else {
+				if _, typed := node["type"]; !typed {
+					node["type"] = "object"
+				}
+				if !hasType(node, "object") {
+					break
+				}
+				props, _ := node["properties"].(map[string]any)
+				if props == nil {
+					props = map[string]any{}
+					node["properties"] = props
+				}
+				child, _ = props[seg].(map[string]any)
+				if child == nil {
+					child = map[string]any{}
+					props[seg] = child
+					added++
+				}
+			}
+			if i == len(ref.Path)-1 {
+				if _, typed := child["type"]; !typed && ref.Type != "" {
+					child["type"] = ref.Type
+				}
+			}
+			node = child
+		}
+	}
+	return added
+}
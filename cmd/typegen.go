@@ -0,0 +1,389 @@
+package cmd
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TypeScript and Go type generation from the inferred schema
+
+// generatedHeader marks generated files so that linters and reviewers skip them
+const generatedHeader = "Code generated by valet. DO NOT EDIT."
+
+// typeDecl is a named object type collected from the schema tree
+type typeDecl struct {
+	Name        string
+	Description string
+	Schema      map[string]any
+}
+
+// typeNamer collects named types for the nested objects of a schema
+type typeNamer struct {
+	decls []*typeDecl
+	used  map[string]bool
+}
+
+// collectTypes names the root schema and every nested object type, breadth-first,
+// so that shallower objects get the shorter names when names collide.
+func collectTypes(schema map[string]any, rootName string) ([]*typeDecl, map[uintptr]string) {
+	n := &typeNamer{used: make(map[string]bool)}
+	names := make(map[uintptr]string)
+
+	type pending struct {
+		schema map[string]any
+		name   string
+		parent string
+	}
+	queue := []pending{{schema: schema, name: rootName}}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		name := p.name
+		if n.used[name] {
+			name = p.parent + p.name
+		}
+		for i := 2; n.used[name]; i++ {
+			name = fmt.Sprintf("%s%s%d", p.parent, p.name, i)
+		}
+		n.used[name] = true
+		desc, _ := p.schema["description"].(string)
+		n.decls = append(n.decls, &typeDecl{Name: name, Description: desc, Schema: p.schema})
+		names[schemaKey(p.schema)] = name
+
+		for _, key := range sortedProperties(p.schema) {
+			prop := propertySchema(p.schema, key)
+			if obj, ok := namedObject(prop); ok {
+				queue = append(queue, pending{schema: obj, name: exportedName(key), parent: name})
+			} else if items, ok := arrayItems(prop); ok {
+				if obj, ok := namedObject(items); ok {
+					queue = append(queue, pending{schema: obj, name: exportedName(key) + "Item", parent: name})
+				}
+			}
+		}
+	}
+	return n.decls, names
+}
+
+// schemaKey identifies a schema node by the address of its map
+func schemaKey(m map[string]any) uintptr {
+	return reflect.ValueOf(m).Pointer()
+}
+
+// namedObject reports whether schema is an object with declared properties,
+// which gets its own named type
+func namedObject(schema map[string]any) (map[string]any, bool) {
+	if schema == nil || !hasType(schema, "object") {
+		return nil, false
+	}
+	props, _ := schema["properties"].(map[string]any)
+	if len(props) == 0 {
+		return nil, false
+	}
+	return schema, true
+}
+
+// arrayItems returns the items schema of an array schema
+func arrayItems(schema map[string]any) (map[string]any, bool) {
+	if schema == nil || !hasType(schema, "array") {
+		return nil, false
+	}
+	items, ok := schema["items"].(map[string]any)
+	return items, ok
+}
+
+// typeList returns the declared types of a schema node
+func typeList(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, v := range t {
+			out = append(out, fmt.Sprint(v))
+		}
+		return out
+	}
+	return nil
+}
+
+// hasType reports whether the schema node allows type t
+func hasType(schema map[string]any, t string) bool {
+	for _, st := range typeList(schema) {
+		if st == t {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedProperties returns the property names of an object schema in sorted order
+func sortedProperties(schema map[string]any) []string {
+	props, _ := schema["properties"].(map[string]any)
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// propertySchema returns the schema of a single property
+func propertySchema(schema map[string]any, key string) map[string]any {
+	props, _ := schema["properties"].(map[string]any)
+	prop, _ := props[key].(map[string]any)
+	return prop
+}
+
+// requiredSet returns the required property names of an object schema
+func requiredSet(schema map[string]any) map[string]bool {
+	set := make(map[string]bool)
+	switch req := schema["required"].(type) {
+	case []string:
+		for _, k := range req {
+			set[k] = true
+		}
+	case []any:
+		for _, k := range req {
+			set[fmt.Sprint(k)] = true
+		}
+	}
+	return set
+}
+
+// exportedName converts a values key into an exported Go/TypeScript identifier
+func exportedName(key string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// typeScriptTypes renders the schema as TypeScript interfaces
+func typeScriptTypes(schema map[string]any, rootName string) []byte {
+	decls, names := collectTypes(schema, rootName)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s\n", generatedHeader)
+	for _, d := range decls {
+		b.WriteString("\n")
+		writeTSDoc(&b, "", d.Description)
+		fmt.Fprintf(&b, "export interface %s {\n", d.Name)
+		required := requiredSet(d.Schema)
+		for _, key := range sortedProperties(d.Schema) {
+			prop := propertySchema(d.Schema, key)
+			desc, _ := prop["description"].(string)
+			writeTSDoc(&b, "  ", desc)
+			optional := "?"
+			if required[key] {
+				optional = ""
+			}
+			fmt.Fprintf(&b, "  %s%s: %s;\n", tsPropertyName(key), optional, tsType(prop, names))
+		}
+		b.WriteString("}\n")
+	}
+	return []byte(b.String())
+}
+
+// tsType returns the TypeScript type for a schema node
+func tsType(schema map[string]any, names map[uintptr]string) string {
+	if schema == nil {
+		return "unknown"
+	}
+	if name, ok := names[schemaKey(schema)]; ok {
+		return name
+	}
+	var parts []string
+	for _, t := range typeList(schema) {
+		switch t {
+		case "string":
+			parts = append(parts, "string")
+		case "integer", "number":
+			parts = append(parts, "number")
+		case "boolean":
+			parts = append(parts, "boolean")
+		case "null":
+			parts = append(parts, "null")
+		case "array":
+			items, _ := arrayItems(schema)
+			item := "unknown"
+			if len(items) > 0 {
+				item = tsType(items, names)
+			}
+			if strings.Contains(item, " ") {
+				item = "(" + item + ")"
+			}
+			parts = append(parts, item+"[]")
+		case "object":
+			parts = append(parts, "Record<string, unknown>")
+		}
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, " | ")
+}
+
+// tsPropertyName quotes keys that are not valid TypeScript identifiers
+func tsPropertyName(key string) string {
+	for i, r := range key {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return fmt.Sprintf("%q", key)
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
+
+// writeTSDoc writes a JSDoc comment for description
+func writeTSDoc(b *strings.Builder, indent, description string) {
+	if description == "" {
+		return
+	}
+	description = strings.Join(strings.Fields(description), " ")
+	fmt.Fprintf(b, "%s/** %s */\n", indent, strings.ReplaceAll(description, "*/", "* /"))
+}
+
+// goTypes renders the schema as Go structs with yaml and json tags
+func goTypes(schema map[string]any, pkg, rootName string) ([]byte, error) {
+	decls, names := collectTypes(schema, rootName)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s\n\npackage %s\n", generatedHeader, pkg)
+	for _, d := range decls {
+		b.WriteString("\n")
+		writeGoDoc(&b, "", d.Name, d.Description)
+		fmt.Fprintf(&b, "type %s struct {\n", d.Name)
+		required := requiredSet(d.Schema)
+		fields := make(map[string]bool)
+		for _, key := range sortedProperties(d.Schema) {
+			prop := propertySchema(d.Schema, key)
+			field := exportedName(key)
+			for i := 2; fields[field]; i++ {
+				field = fmt.Sprintf("%s%d", exportedName(key), i)
+			}
+			fields[field] = true
+
+			desc, _ := prop["description"].(string)
+			writeGoDoc(&b, "\t", "", desc)
+			tag := key
+			typ := goType(prop, names)
+			if !required[key] {
+				tag += ",omitempty"
+				if !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") &&
+					!strings.HasPrefix(typ, "*") && typ != "any" {
+					typ = "*" + typ
+				}
+			}
+			fmt.Fprintf(&b, "\t%s %s `yaml:%q json:%q`\n", field, typ, tag, tag)
+		}
+		b.WriteString("}\n")
+	}
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("error formatting Go types: %w", err)
+	}
+	return src, nil
+}
+
+// goType returns the Go type for a schema node
+func goType(schema map[string]any, names map[uintptr]string) string {
+	if schema == nil {
+		return "any"
+	}
+	if name, ok := names[schemaKey(schema)]; ok {
+		return name
+	}
+	types := typeList(schema)
+	nullable := false
+	var nonNull []string
+	for _, t := range types {
+		if t == "null" {
+			nullable = true
+			continue
+		}
+		nonNull = append(nonNull, t)
+	}
+	if len(nonNull) != 1 {
+		return "any"
+	}
+	var typ string
+	switch nonNull[0] {
+	case "string":
+		typ = "string"
+	case "integer":
+		typ = "int64"
+	case "number":
+		typ = "float64"
+	case "boolean":
+		typ = "bool"
+	case "array":
+		items, _ := arrayItems(schema)
+		item := "any"
+		if len(items) > 0 {
+			item = goType(items, names)
+		}
+		return "[]" + item
+	case "object":
+		return "map[string]any"
+	default:
+		return "any"
+	}
+	if nullable {
+		typ = "*" + typ
+	}
+	return typ
+}
+
+// writeGoDoc writes a Go doc comment
+func writeGoDoc(b *strings.Builder, indent, name, description string) {
+	description = strings.Join(strings.Fields(description), " ")
+	switch {
+	case description != "" && name != "":
+		fmt.Fprintf(b, "%s// %s %s\n", indent, name, description)
+	case description != "":
+		fmt.Fprintf(b, "%s// %s\n", indent, description)
+	case name != "":
+		fmt.Fprintf(b, "%s// %s is generated from the chart values\n", indent, name)
+	}
+}
+
+// goPackageName derives a package name from the output directory, defaulting to "values"
+func goPackageName(dir string) string {
+	name := strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return -1
+	}, dir))
+	if name == "" || !token.IsIdentifier(name) || token.IsKeyword(name) || unicode.IsDigit([]rune(name)[0]) {
+		return "values"
+	}
+	return name
+}
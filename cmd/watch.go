@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watch mode for the generate subcommand
+
+// watchDebounce is how long to wait for a burst of file events to settle before regenerating
+const watchDebounce = 250 * time.Millisecond
+
+// WatchOptions configures Watch
+type WatchOptions struct {
+	// ConfigFiles are the config files to watch in addition to the values files (optional)
+	ConfigFiles []string
+	// Reload is called before regenerating when one of ConfigFiles changed (optional)
+	Reload func() error
+	// Out receives the summary of every regeneration
+	Out io.Writer
+}
+
+// Watch generates the schema for ctxDir and regenerates it whenever values.yaml, the overrides
+// file, the schema overlay, the chart templates or the config files change, until ctx is canceled.
+func Watch(ctx context.Context, ctxDir, overridesFlag string, opts WatchOptions) error {
+	tel := GetTelemetry()
+
+	// Metrics exported to Prometheus are served for as long as the watch runs
+	if addr, err := tel.ServeMetrics(); err != nil {
+		fmt.Fprintf(opts.Out, "Error: %v\n", err)
+	} else if addr != "" {
+		fmt.Fprintf(opts.Out, "Serving metrics on http://%s/metrics\n", addr)
+	}
+
+	var previous map[string]any
+	regenerate := func() {
+		schema, err := buildSchema(ctx, tel, ctxDir, overridesFlag)
+		if err == nil {
+			var outPath string
+			outPath, err = writeSchema(ctx, tel, ctxDir, schema)
+			if err == nil {
+				printSchemaChanges(opts.Out, outPath, previous, schema)
+				previous = schema
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(opts.Out, "Error: %v\n", err)
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error creating file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Watch the parent directories rather than the files themselves so that editors
+	// which save by renaming a temporary file over the original keep being tracked.
+	files := watchedFiles(ctxDir, overridesFlag, opts.ConfigFiles)
+	configFiles := make(map[string]bool)
+	for _, file := range opts.ConfigFiles {
+		configFiles[absPath(file)] = true
+	}
+	// Every file of the template directories counts, as templates feed the schema through
+	// their .Values references. The chart directory is watched for templates/ to be created.
+	templatesDir := absPath(filepath.Join(ctxDir, "templates"))
+	templateDirs := watchedTemplateDirs(ctxDir)
+	dirs := make(map[string]bool)
+	watchDir := func(dir string) error {
+		if dirs[dir] {
+			return nil
+		}
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("error watching %s: %w", dir, err)
+		}
+		dirs[dir] = true
+		return nil
+	}
+	for file := range files {
+		if err := watchDir(filepath.Dir(file)); err != nil {
+			return err
+		}
+	}
+	for dir := range templateDirs {
+		if err := watchDir(dir); err != nil {
+			return err
+		}
+	}
+	if err := watchDir(filepath.Dir(templatesDir)); err != nil {
+		return err
+	}
+
+	regenerate()
+	fmt.Fprintf(opts.Out, "Watching %s for changes (press Ctrl+C to stop)\n", ctxDir)
+
+	var (
+		timer         *time.Timer
+		pending       <-chan time.Time
+		configChanged string
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			name, err := filepath.Abs(event.Name)
+			if err != nil {
+				continue
+			}
+			// Watch template directories created after the watch started, with their contents
+			if event.Has(fsnotify.Create) && (name == templatesDir || templateDirs[filepath.Dir(name)]) {
+				if info, err := os.Stat(name); err == nil && info.IsDir() {
+					for dir := range watchedTemplateDirs(ctxDir) {
+						if err := watchDir(dir); err != nil {
+							fmt.Fprintf(opts.Out, "Watch error: %v\n", err)
+							continue
+						}
+						templateDirs[dir] = true
+					}
+				}
+			}
+			// A removed directory is no longer watched, so that it is added again if recreated
+			if (event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)) && templateDirs[name] {
+				delete(templateDirs, name)
+				delete(dirs, name)
+			}
+			if !files[name] && !templateDirs[filepath.Dir(name)] && name != templatesDir {
+				continue
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) &&
+				!event.Has(fsnotify.Rename) && !event.Has(fsnotify.Remove) {
+				continue
+			}
+			if configFiles[name] {
+				configChanged = name
+			}
+			if timer == nil {
+				timer = time.NewTimer(watchDebounce)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+			pending = timer.C
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(opts.Out, "Watch error: %v\n", err)
+		case <-pending:
+			pending = nil
+			if configChanged != "" && opts.Reload != nil {
+				if err := opts.Reload(); err != nil {
+					fmt.Fprintf(opts.Out, "Error reloading %s: %v\n", configChanged, err)
+				}
+			}
+			configChanged = ""
+			regenerate()
+		}
+	}
+}
+
+// watchedFiles returns the absolute paths of every input file of a generation
+func watchedFiles(ctxDir, overridesFlag string, configFiles []string) map[string]bool {
+	files := make(map[string]bool)
+	for _, name := range valuesFileNames {
+		files[absPath(filepath.Join(ctxDir, name))] = true
+	}
+	if valuesPath, err := findValuesFile(ctxDir); err == nil {
+		files[absPath(valuesPath)] = true
+	}
+	if overridesFlag != "" {
+		files[absPath(filepath.Join(ctxDir, overridesFlag))] = true
+	}
+	overlayPath, _ := resolveOverlayPath(ctxDir)
+	files[absPath(overlayPath)] = true
+	for _, file := range configFiles {
+		files[absPath(file)] = true
+	}
+	return files
+}
+
+// watchedTemplateDirs returns the absolute paths of the chart's templates directory and its
+// subdirectories, or none when the chart has no templates
+func watchedTemplateDirs(ctxDir string) map[string]bool {
+	dirs := make(map[string]bool)
+	_ = filepath.WalkDir(filepath.Join(ctxDir, "templates"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			dirs[absPath(path)] = true
+		}
+		return nil
+	})
+	return dirs
+}
+
+// absPath returns the absolute form of path, falling back to the cleaned path
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// schemaField summarizes a single property of a schema for change detection
+type schemaField struct {
+	Type     string
+	Default  string
+	Required bool
+}
+
+// printSchemaChanges writes a compact summary of the differences between two schemas
+func printSchemaChanges(out io.Writer, outPath string, before, after map[string]any) {
+	if before == nil {
+		fmt.Fprintf(out, "Generated %s\n", outPath)
+		return
+	}
+	changes := schemaChanges(before, after)
+	if len(changes) == 0 {
+		fmt.Fprintf(out, "Regenerated %s: no schema changes\n", outPath)
+		return
+	}
+	var added, removed, changed int
+	for _, c := range changes {
+		switch c[0] {
+		case '+':
+			added++
+		case '-':
+			removed++
+		default:
+			changed++
+		}
+	}
+	fmt.Fprintf(out, "Regenerated %s: %d added, %d removed, %d changed\n", outPath, added, removed, changed)
+	for _, c := range changes {
+		fmt.Fprintf(out, "  %s\n", c)
+	}
+}
+
+// schemaChanges lists the properties that were added, removed or changed between two schemas
+func schemaChanges(before, after map[string]any) []string {
+	old := make(map[string]schemaField)
+	flattenSchema(before, "", false, old)
+	cur := make(map[string]schemaField)
+	flattenSchema(after, "", false, cur)
+
+	var changes []string
+	for path, f := range cur {
+		prev, ok := old[path]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("+ %s (%s)", path, f.Type))
+			continue
+		}
+		var diffs []string
+		if prev.Type != f.Type {
+			diffs = append(diffs, fmt.Sprintf("type %s -> %s", prev.Type, f.Type))
+		}
+		if prev.Default != f.Default {
+			diffs = append(diffs, fmt.Sprintf("default %s -> %s", prev.Default, f.Default))
+		}
+		if prev.Required != f.Required {
+			diffs = append(diffs, fmt.Sprintf("required %t -> %t", prev.Required, f.Required))
+		}
+		if len(diffs) > 0 {
+			changes = append(changes, fmt.Sprintf("~ %s: %s", path, strings.Join(diffs, ", ")))
+		}
+	}
+	for path := range old {
+		if _, ok := cur[path]; !ok {
+			changes = append(changes, fmt.Sprintf("- %s", path))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i][2:] < changes[j][2:]
+	})
+	return changes
+}
+
+// flattenSchema records a schemaField for every property below schema, keyed by dotted path
+func flattenSchema(schema map[string]any, prefix string, required bool, fields map[string]schemaField) {
+	if prefix != "" {
+		f := schemaField{Type: fmt.Sprint(schema["type"]), Required: required}
+		// Object and array defaults change with their children; only compare scalars
+		if !reflect.DeepEqual(schema["type"], "object") && !reflect.DeepEqual(schema["type"], "array") {
+			if data, err := json.Marshal(schema["default"]); err == nil {
+				f.Default = string(data)
+			}
+		}
+		fields[prefix] = f
+	}
+
+	requiredKeys := make(map[string]bool)
+	switch req := schema["required"].(type) {
+	case []string:
+		for _, k := range req {
+			requiredKeys[k] = true
+		}
+	case []any:
+		for _, k := range req {
+			requiredKeys[fmt.Sprint(k)] = true
+		}
+	}
+
+	if props, ok := schema["properties"].(map[string]any); ok {
+		for key, prop := range props {
+			if propMap, ok := prop.(map[string]any); ok {
+				path := key
+				if prefix != "" {
+					path = prefix + "." + key
+				}
+				flattenSchema(propMap, path, requiredKeys[key], fields)
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]any); ok && len(items) > 0 {
+		flattenSchema(items, prefix+"[*]", false, fields)
+	}
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Consistency checks over the YAML node tree of a values file
+
+// lintRule describes a lint rule
+type lintRule struct {
+	ID          string
+	Description string
+}
+
+// lintRules lists the rules of valet lint, in the order they are documented
+var lintRules = []lintRule{
+	{ID: "unused-value", Description: "Value is not used by any template or subchart"},
+	{ID: "duplicate-key", Description: "Key is defined more than once in the same mapping; the last definition silently wins"},
+	{ID: "mixed-types", Description: "Elements of a list have different types"},
+	{ID: "null-string", Description: `The string "null" is used where null was probably meant`},
+	{ID: "boolean-string", Description: "A boolean is written as a quoted string"},
+	{ID: "norway-boolean", Description: "A plain yes/no/on/off scalar is parsed as a boolean by YAML 1.1 parsers such as Helm"},
+	{ID: "ambiguous-number", Description: "A plain scalar is parsed as a float or an octal number, which changes how it is written"},
+	{ID: "undocumented-key", Description: "Key has no documenting comment, and neither has any of its parents"},
+}
+
+// octalPattern matches integers with a leading zero, which YAML 1.1 parsers (including Helm) read as octal
+var octalPattern = regexp.MustCompile(`^[-+]?0[0-7]+$|^0o[0-7]+$`)
+
+// valuesChecker walks a values node tree and collects findings
+type valuesChecker struct {
+	file     string
+	findings []lintFinding
+}
+
+// checkValues runs the node-level lint rules on the root node of a values file
+func checkValues(file string, root *yaml.Node) []lintFinding {
+	c := &valuesChecker{file: file}
+	c.check(root, nil, true)
+	return c.findings
+}
+
+// report adds a finding for node
+func (c *valuesChecker) report(rule, severity string, node *yaml.Node, path []string, format string, args ...any) {
+	c.findings = append(c.findings, lintFinding{
+		Rule:     rule,
+		Severity: severity,
+		File:     c.file,
+		Line:     node.Line,
+		Column:   node.Column,
+		Path:     valuesPathString(path),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// check applies the rules to node at path; documented reports whether keys of mappings
+// at this level still need a comment. A comment on a key documents everything below it,
+// and keys inside list elements do not need one.
+func (c *valuesChecker) check(node *yaml.Node, path []string, documented bool) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		seen := make(map[string]*yaml.Node)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := appendPath(path, key.Value)
+			if isNorwayBoolean(key) {
+				c.report("norway-boolean", severityWarning, key, childPath,
+					"key %s is parsed as the boolean %t by YAML 1.1 parsers such as Helm; quote it",
+					key.Value, norwayValue(key.Value))
+			}
+			if first, ok := seen[key.Value]; ok {
+				c.report("duplicate-key", severityError, key, childPath,
+					"%s is already defined at line %d; the last definition wins", valuesPathString(childPath), first.Line)
+			} else {
+				seen[key.Value] = key
+			}
+			needsDoc := documented && key.HeadComment == "" && key.LineComment == ""
+			if needsDoc {
+				c.report("undocumented-key", severityInfo, key, childPath,
+					"%s has no documenting comment", valuesPathString(childPath))
+			}
+			// Only the topmost undocumented key of a subtree is reported
+			c.check(value, childPath, documented && !needsDoc)
+		}
+	case yaml.SequenceNode:
+		itemPath := appendPath(path, "[*]")
+		c.checkMixedTypes(node, itemPath)
+		for _, item := range node.Content {
+			c.check(item, itemPath, false)
+		}
+	case yaml.ScalarNode:
+		c.checkScalar(node, path)
+	}
+}
+
+// checkMixedTypes reports the first element of a list whose type differs from the first element
+func (c *valuesChecker) checkMixedTypes(node *yaml.Node, path []string) {
+	var firstType string
+	for _, item := range node.Content {
+		t := yamlNodeType(item)
+		if t == "" || t == "null" {
+			continue
+		}
+		if firstType == "" {
+			firstType = t
+			continue
+		}
+		if t != firstType {
+			c.report("mixed-types", severityWarning, item, path,
+				"%s mixes %s and %s elements", valuesPathString(path), firstType, t)
+			return
+		}
+	}
+}
+
+// checkScalar applies the rules for scalar values
+func (c *valuesChecker) checkScalar(node *yaml.Node, path []string) {
+	quoted := node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0
+	p := valuesPathString(path)
+	switch {
+	case quoted && node.Tag == "!!str" && isNullWord(node.Value):
+		c.report("null-string", severityWarning, node, path,
+			"%s is the string %q; write null (unquoted) for a null value", p, node.Value)
+	case quoted && node.Tag == "!!str" && isBoolWord(node.Value):
+		c.report("boolean-string", severityWarning, node, path,
+			"%s is the string %q; write %s (unquoted) for a boolean", p, node.Value, strings.ToLower(node.Value))
+	case isNorwayBoolean(node):
+		c.report("norway-boolean", severityWarning, node, path,
+			"%s: %s is parsed as the boolean %t by YAML 1.1 parsers such as Helm; quote it for a string, or write %t",
+			p, node.Value, norwayValue(node.Value), norwayValue(node.Value))
+	case !quoted && node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0:
+		if msg := ambiguousNumber(node); msg != "" {
+			c.report("ambiguous-number", severityWarning, node, path, "%s: %s", p, msg)
+		}
+	}
+}
+
+// ambiguousNumber explains how a plain scalar that looks like a number is parsed, when
+// that changes how it is written; it returns "" for unambiguous scalars
+func ambiguousNumber(node *yaml.Node) string {
+	if octalPattern.MatchString(node.Value) {
+		return fmt.Sprintf("%s is parsed as an octal number; quote it if it is a string, or write it in decimal", node.Value)
+	}
+	if node.Tag != "!!float" {
+		return ""
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(node.Value, "_", ""), 64)
+	if err != nil {
+		return ""
+	}
+	if formatted := strconv.FormatFloat(f, 'f', -1, 64); formatted != node.Value {
+		return fmt.Sprintf("%s is parsed as the float %s; quote it if it is a string such as a version", node.Value, formatted)
+	}
+	return ""
+}
+
+// yamlNodeType returns the JSON type of a node: object, array, string, number, boolean or null
+func yamlNodeType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	case yaml.ScalarNode:
+		switch node.Tag {
+		case "!!int", "!!float":
+			return "number"
+		case "!!bool":
+			return "boolean"
+		case "!!null":
+			return "null"
+		}
+		return "string"
+	}
+	return ""
+}
+
+// isNullWord reports whether s is a YAML null literal
+func isNullWord(s string) bool {
+	switch s {
+	case "null", "Null", "NULL", "~":
+		return true
+	}
+	return false
+}
+
+// isNorwayBoolean reports whether node is a plain scalar that YAML 1.2 reads as a string
+// but YAML 1.1 (yaml.v2, and therefore Helm) reads as a boolean
+func isNorwayBoolean(node *yaml.Node) bool {
+	if node.Kind != yaml.ScalarNode || node.Style != 0 || node.Tag != "!!str" {
+		return false
+	}
+	switch node.Value {
+	case "y", "Y", "yes", "Yes", "YES", "on", "On", "ON",
+		"n", "N", "no", "No", "NO", "off", "Off", "OFF":
+		return true
+	}
+	return false
+}
+
+// norwayValue returns the boolean a YAML 1.1 parser reads for a yes/no/on/off scalar
+func norwayValue(s string) bool {
+	switch strings.ToLower(s) {
+	case "y", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// isBoolWord reports whether s is a YAML 1.2 boolean literal
+func isBoolWord(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "false":
+		return true
+	}
+	return false
+}
+
+// Strict loading modes for values files
+const (
+	strictOff   = "off"
+	strictWarn  = "warn"
+	strictError = "error"
+)
+
+// strictRules are the rules checked when loading values files in strict mode: the problems
+// that make the loaded values differ from what the chart author wrote
+var strictRules = map[string]bool{
+	"duplicate-key":    true,
+	"norway-boolean":   true,
+	"ambiguous-number": true,
+}
+
+// strictMode returns the strict loading mode configured for ctxDir (default: off)
+func strictMode(ctxDir string) string {
+	if c := configFor(ctxDir); c != nil && c.Strict != "" {
+		return c.Strict
+	}
+	return strictOff
+}
+
+// checkStrict reports the strict rule findings of a values file
+func checkStrict(file string, data []byte) ([]lintFinding, error) {
+	docs, err := parseDocuments(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", file, err)
+	}
+	var findings []lintFinding
+	for _, doc := range docs {
+		for _, f := range checkValues(file, doc) {
+			if strictRules[f.Rule] {
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings, nil
+}
+
+// parseDocuments parses every document of a YAML stream and returns their root nodes,
+// skipping empty documents
+func parseDocuments(data []byte) ([]*yaml.Node, error) {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return docs, nil
+			}
+			return nil, err
+		}
+		if len(doc.Content) > 0 {
+			docs = append(docs, doc.Content[0])
+		}
+	}
+}
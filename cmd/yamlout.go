@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAML output of the generated schema
+
+// schemaKeywordOrder is the order in which known keywords are written for each schema node;
+// other keywords follow in alphabetical order
+var schemaKeywordOrder = []string{
+	"$schema", "title", "description", "type", "format", "pattern", "enum",
+	"minimum", "maximum", "default", "properties", "items", "required",
+}
+
+// valuesLayout records the key order and comments of a values file by values path
+type valuesLayout struct {
+	order    map[string][]string
+	comments map[string]string
+}
+
+// loadValuesLayout reads the key order and head comments from a values file.
+// A missing or unparsable file yields an empty layout, so output falls back to sorted keys.
+func loadValuesLayout(path string) *valuesLayout {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return parseValuesLayout(nil)
+	}
+	return parseValuesLayout(data)
+}
+
+// parseValuesLayout reads the key order and head comments from values data
+func parseValuesLayout(data []byte) *valuesLayout {
+	layout := &valuesLayout{order: map[string][]string{}, comments: map[string]string{}}
+	docs, err := parseDocuments(data)
+	if err != nil {
+		return layout
+	}
+	for _, doc := range docs {
+		layout.collect(doc, "")
+	}
+	return layout
+}
+
+// collect walks node and records the layout of every mapping below it
+func (l *valuesLayout) collect(node *yaml.Node, path string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			childPath := joinValuesPath(path, key.Value)
+			if _, seen := l.comments[childPath]; !seen {
+				l.order[path] = append(l.order[path], key.Value)
+			}
+			l.comments[childPath] = strings.TrimSpace(key.HeadComment)
+			l.collect(node.Content[i+1], childPath)
+		}
+	case yaml.SequenceNode:
+		if len(node.Content) > 0 {
+			l.collect(node.Content[0], path+"[*]")
+		}
+	}
+}
+
+// joinValuesPath appends key to a dotted values path
+func joinValuesPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// orderedKeys returns the keys of m in values file order, followed by the remaining keys sorted
+func (l *valuesLayout) orderedKeys(m map[string]any, path string) []string {
+	keys := make([]string, 0, len(m))
+	seen := make(map[string]bool, len(m))
+	for _, k := range l.order[path] {
+		if _, ok := m[k]; ok && !seen[k] {
+			keys = append(keys, k)
+			seen[k] = true
+		}
+	}
+	var rest []string
+	for k := range m {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+// orderedRequired returns the required property names in values file order
+func (l *valuesLayout) orderedRequired(required map[string]bool, path string) []any {
+	m := make(map[string]any, len(required))
+	for k := range required {
+		m[k] = true
+	}
+	keys := l.orderedKeys(m, path)
+	out := make([]any, len(keys))
+	for i, k := range keys {
+		out[i] = k
+	}
+	return out
+}
+
+// schemaKeys returns the keywords of a schema node in schemaKeywordOrder
+func schemaKeys(schema map[string]any) []string {
+	keys := make([]string, 0, len(schema))
+	seen := make(map[string]bool, len(schema))
+	for _, k := range schemaKeywordOrder {
+		if _, ok := schema[k]; ok {
+			keys = append(keys, k)
+			seen[k] = true
+		}
+	}
+	var rest []string
+	for k := range schema {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+// schemaYAML renders schema as YAML, keeping the key order and comments of the values file
+func schemaYAML(schema map[string]any, layout *valuesLayout) ([]byte, error) {
+	root, err := schemaNode(schema, "", layout)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n", generatedHeader)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, fmt.Errorf("error marshaling YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("error marshaling YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// schemaNode converts a schema node at values path into a YAML node
+func schemaNode(schema map[string]any, path string, layout *valuesLayout) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, keyword := range schemaKeys(schema) {
+		var value *yaml.Node
+		var err error
+		switch v := schema[keyword].(type) {
+		case map[string]any:
+			switch keyword {
+			case "properties":
+				value, err = propertiesNode(v, path, layout)
+			case "items":
+				value, err = schemaNode(v, path+"[*]", layout)
+			case "default":
+				value, err = valueNode(v, path, layout)
+			default:
+				value, err = valueNode(v, "", &valuesLayout{})
+			}
+		default:
+			if keyword == "required" {
+				v = layout.orderedRequired(requiredSet(schema), path)
+			}
+			value, err = valueNode(v, path, layout)
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, scalarKey(keyword, ""), value)
+	}
+	return node, nil
+}
+
+// propertiesNode converts the properties of an object schema, commenting each property
+// with the comment of the corresponding key in the values file
+func propertiesNode(props map[string]any, path string, layout *valuesLayout) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range layout.orderedKeys(props, path) {
+		childPath := joinValuesPath(path, key)
+		var value *yaml.Node
+		var err error
+		if prop, ok := props[key].(map[string]any); ok {
+			value, err = schemaNode(prop, childPath, layout)
+		} else {
+			value, err = valueNode(props[key], childPath, layout)
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, scalarKey(key, stripAnnotations(layout.comments[childPath])), value)
+	}
+	return node, nil
+}
+
+// valueNode converts plain data, such as defaults and enums, into a YAML node
+func valueNode(v any, path string, layout *valuesLayout) (*yaml.Node, error) {
+	switch val := v.(type) {
+	case map[string]any:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, key := range layout.orderedKeys(val, path) {
+			child, err := valueNode(val[key], joinValuesPath(path, key), layout)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, scalarKey(key, ""), child)
+		}
+		return node, nil
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range val {
+			child, err := valueNode(item, path+"[*]", layout)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(v); err != nil {
+		return nil, fmt.Errorf("error marshaling YAML: %w", err)
+	}
+	return node, nil
+}
+
+// scalarKey builds a mapping key node with an optional head comment
+func scalarKey(key, comment string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key, HeadComment: comment}
+}
@@ -2,18 +2,70 @@ package config
 
 import (
 	"fmt"
-	"os"
-
-	"gopkg.in/yaml.v2"
+	"time"
 )
 
 // Config holds the configuration for the application
 type Config struct {
-	Debug     bool             `yaml:"debug"`
-	Context   string           `yaml:"context"`
-	Overrides string           `yaml:"overrides"`
-	Output    string           `yaml:"output"`
+	Debug   bool   `yaml:"debug"`
+	Context string `yaml:"context"`
+	// Values is the values file (relative to the context dir), overriding the lookup of
+	// values.yaml, values.yml and values.json
+	Values    string `yaml:"values"`
+	Overrides string `yaml:"overrides"`
+	Output    string `yaml:"output"`
+	// Format is the output format: json (default), yaml, typescript or go
+	Format string `yaml:"format"`
+	// SchemaOverlay is the path (relative to the context dir) of a file holding
+	// JSON Schema fragments keyed by dotted values path (default: values.schema.overlay.yaml)
+	SchemaOverlay string `yaml:"schemaOverlay"`
+	// Strict controls how values files are checked on load for duplicate keys,
+	// YAML 1.1 booleans and ambiguous numbers: off (default), warn or error
+	Strict string `yaml:"strict"`
+	// Cache skips generating schemas whose inputs have not changed since they were
+	// last generated, tracked by content hash in the user cache directory
+	Cache bool `yaml:"cache"`
+	// Draft is the JSON Schema draft the generated schema declares in $schema:
+	// draft-04, draft-06, draft-07, 2019-09 or 2020-12 (default: unversioned)
+	Draft string `yaml:"draft"`
+	// Charts holds settings for individual charts of a repository
+	Charts []ChartConfig `yaml:"charts"`
+	// Rules adjust the inferred schema of the values matching their path
+	Rules     []Rule           `yaml:"rules"`
 	Telemetry *TelemetryConfig `yaml:"telemetry"`
+	// Origins records where each setting came from (a file, an environment variable or a
+	// flag), by dotted YAML key; settings without an entry have their default value
+	Origins map[string]string `yaml:"-"`
+}
+
+// ChartConfig holds the settings of a single chart. They override the top-level settings
+// of the config files for that chart; environment variables and flags still override them.
+type ChartConfig struct {
+	// Context is the chart directory, relative to the config file declaring it
+	Context string `yaml:"context"`
+	// Overrides is the overrides file, relative to the chart directory
+	Overrides string `yaml:"overrides"`
+	// Output is the output file, relative to the chart directory
+	Output string `yaml:"output"`
+	// Draft is the JSON Schema draft of the chart's schema
+	Draft string `yaml:"draft"`
+	// Rules are applied after the top-level rules
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule adjusts the schema of every value whose path matches Path, a dotted values path
+// in which "*" matches any single key and "**" any number of keys, e.g. "*.resources"
+// or "ingress.hosts[*].host"
+type Rule struct {
+	Path        string `yaml:"path"`
+	Type        string `yaml:"type"`
+	Format      string `yaml:"format"`
+	Enum        []any  `yaml:"enum"`
+	Description string `yaml:"description"`
+	// Required adds the value to (true) or removes it from (false) the required properties
+	Required *bool `yaml:"required"`
+	// Ignore removes the value from the schema
+	Ignore bool `yaml:"ignore"`
 }
 
 // TelemetryConfig holds the telemetry configuration
@@ -24,9 +76,10 @@ type TelemetryConfig struct {
 	ServiceName string `yaml:"serviceName"`
 	// ServiceVersion overrides the default service version
 	ServiceVersion string `yaml:"serviceVersion"`
-	// ExporterType determines the exporter type (otlp, stdout, none)
+	// ExporterType determines the exporter type (otlp, otlphttp, file, stdout, none)
 	ExporterType string `yaml:"exporterType"`
-	// OTLPEndpoint is the OTLP endpoint for traces and metrics
+	// OTLPEndpoint is the OTLP endpoint for traces, metrics and logs (default: localhost:4317,
+	// or localhost:4318 for otlphttp)
 	OTLPEndpoint string `yaml:"otlpEndpoint"`
 	// Insecure determines if the OTLP connection should be insecure
 	Insecure bool `yaml:"insecure"`
@@ -34,8 +87,139 @@ type TelemetryConfig struct {
 	Headers map[string]string `yaml:"headers"`
 	// SampleRate is the trace sampling rate (0.0 to 1.0)
 	SampleRate float64 `yaml:"sampleRate"`
+	// Compression is the compression of OTLP requests (gzip, none)
+	Compression string `yaml:"compression"`
+	// Timeout is the number of seconds an OTLP export may take (default: 10)
+	Timeout int `yaml:"timeout"`
+	// TLS holds the certificates of OTLP connections
+	TLS TLSConfig `yaml:"tls"`
+	// FilePath is the file the file exporter appends OTLP JSON lines to
+	// (default: valet-telemetry.jsonl)
+	FilePath string `yaml:"filePath"`
+	// Tracing overrides the exporter settings above for traces
+	Tracing TracingConfig `yaml:"tracing,omitempty"`
+	// Metrics overrides the exporter settings above for metrics
+	Metrics MetricsConfig `yaml:"metrics,omitempty"`
+	// Logs overrides the exporter settings above for logs
+	Logs LogsConfig `yaml:"logs,omitempty"`
+}
+
+// SignalConfig holds the exporter settings of a single signal. Settings that are not set
+// fall back to those of the telemetry config.
+type SignalConfig struct {
+	// Enabled turns the signal off when false
+	Enabled *bool `yaml:"enabled,omitempty"`
+	// Exporter is the exporter type (otlp, otlphttp, file, stdout, none)
+	Exporter string `yaml:"exporter,omitempty"`
+	// Endpoint is the OTLP endpoint
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// URLPath is the URL path of the otlphttp exporter (default: /v1/traces, /v1/metrics or /v1/logs)
+	URLPath string `yaml:"urlPath,omitempty"`
+	// Insecure determines if the OTLP connection should be insecure
+	Insecure *bool `yaml:"insecure,omitempty"`
+	// Headers are added to the headers of the telemetry config, overriding them
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// ExportInterval is the number of seconds between exports (default: 5 for traces,
+	// 30 for metrics)
+	ExportInterval int `yaml:"exportInterval,omitempty"`
+	// Compression overrides the compression of the telemetry config
+	Compression string `yaml:"compression,omitempty"`
+	// Timeout overrides the export timeout of the telemetry config
+	Timeout int `yaml:"timeout,omitempty"`
+	// TLS overrides the certificates of the telemetry config, file by file
+	TLS TLSConfig `yaml:"tls,omitempty"`
+	// FilePath overrides the file of the file exporter
+	FilePath string `yaml:"filePath,omitempty"`
+}
+
+// TLSConfig holds the PEM files of a TLS connection. Setting any of them makes the
+// connection secure, whatever the insecure setting.
+type TLSConfig struct {
+	// CAFile is the CA certificate the server certificate is verified with
+	// (default: the system roots)
+	CAFile string `yaml:"caFile,omitempty"`
+	// CertFile and KeyFile are the client certificate and key, for mutual TLS
+	CertFile string `yaml:"certFile,omitempty"`
+	KeyFile  string `yaml:"keyFile,omitempty"`
+}
+
+// IsZero reports whether no TLS file is set
+func (t TLSConfig) IsZero() bool {
+	return t.CAFile == "" && t.CertFile == "" && t.KeyFile == ""
+}
+
+// TracingConfig holds the exporter settings of traces
+type TracingConfig struct {
+	SignalConfig `yaml:",inline"`
+	// SampleRate overrides the trace sampling rate of the telemetry config
+	SampleRate *float64 `yaml:"sampleRate,omitempty"`
+}
+
+// MetricsConfig holds the exporter settings of metrics
+type MetricsConfig struct {
+	SignalConfig `yaml:",inline"`
+	// Prometheus holds the settings of the prometheus exporter
+	Prometheus PrometheusConfig `yaml:"prometheus,omitempty"`
+}
+
+// LogsConfig holds the exporter settings of logs
+type LogsConfig struct {
+	SignalConfig `yaml:",inline"`
+	// Level is the minimum level of the exported logs (debug, info, warn, error; default: info)
+	Level string `yaml:"level,omitempty"`
+}
+
+// PrometheusConfig holds the settings of the prometheus metrics exporter. Long-running
+// commands (generate --watch, lsp) serve the metrics; other commands write them to a file
+// for the node_exporter textfile collector when they exit.
+type PrometheusConfig struct {
+	// Listen is the address /metrics is served on (default: localhost:9464)
+	Listen string `yaml:"listen,omitempty"`
+	// Textfile is the .prom file the metrics are written to (default: valet.prom)
+	Textfile string `yaml:"textfile,omitempty"`
 }
 
+// Exporter is the effective exporter configuration of a signal
+type Exporter struct {
+	// Type is the exporter type (otlp, otlphttp, file, stdout, none); none when the signal is disabled
+	Type     string
+	Endpoint string
+	// URLPath is the URL path of the otlphttp exporter, or "" for its default
+	URLPath  string
+	Insecure bool
+	Headers  map[string]string
+	// Interval is the time between exports
+	Interval time.Duration
+	// Compression is gzip, none or "" for the exporter's default
+	Compression string
+	// Timeout is the export timeout, or 0 for the exporter's default
+	Timeout time.Duration
+	TLS     TLSConfig
+	// FilePath is the file of the file exporter
+	FilePath string
+	// Prometheus holds the settings of the prometheus exporter (metrics only)
+	Prometheus PrometheusConfig
+}
+
+// Default export intervals of the signals
+const (
+	DefaultTraceInterval  = 5 * time.Second
+	DefaultMetricInterval = 30 * time.Second
+	DefaultLogInterval    = time.Second
+)
+
+// DefaultLogLevel is the minimum level of the exported logs
+const DefaultLogLevel = "info"
+
+// Default files and addresses of the exporters, relative to the working directory
+const (
+	DefaultFilePath           = "valet-telemetry.jsonl"
+	DefaultPrometheusListen   = "localhost:9464"
+	DefaultPrometheusTextfile = "valet.prom"
+	DefaultOTLPEndpoint       = "localhost:4317"
+	DefaultOTLPHTTPEndpoint   = "localhost:4318"
+)
+
 // NewTelemetryConfig returns the default telemetry configuration
 func NewTelemetryConfig() *TelemetryConfig {
 	return &TelemetryConfig{
@@ -43,7 +227,6 @@ func NewTelemetryConfig() *TelemetryConfig {
 		ServiceName:    "valet",
 		ServiceVersion: "0.1.0",
 		ExporterType:   "none",
-		OTLPEndpoint:   "localhost:4317",
 		Insecure:       true,
 		Headers:        make(map[string]string),
 		SampleRate:     1.0,
@@ -55,38 +238,172 @@ func (c *TelemetryConfig) Validate() error {
 	if c == nil {
 		return fmt.Errorf("telemetry config is nil")
 	}
-	if c.ExporterType != "otlp" && c.ExporterType != "stdout" && c.ExporterType != "none" {
-		return fmt.Errorf("invalid exporter type: %s", c.ExporterType)
+	if !contains(ExporterTypes, c.ExporterType) {
+		return exporterTypeError(c.ExporterType)
 	}
 	if c.SampleRate < 0.0 || c.SampleRate > 1.0 {
 		return fmt.Errorf("sample rate must be between 0.0 and 1.0, got: %f", c.SampleRate)
 	}
+	if err := validateTransport(c.Compression, c.Timeout, c.TLS); err != nil {
+		return err
+	}
+	if err := c.Tracing.validate(ExporterTypes); err != nil {
+		return fmt.Errorf("tracing: %w", err)
+	}
+	if rate := c.Tracing.SampleRate; rate != nil && (*rate < 0.0 || *rate > 1.0) {
+		return fmt.Errorf("tracing: sample rate must be between 0.0 and 1.0, got: %f", *rate)
+	}
+	if err := c.Metrics.validate(MetricExporterTypes); err != nil {
+		return fmt.Errorf("metrics: %w", err)
+	}
+	if err := c.Logs.validate(ExporterTypes); err != nil {
+		return fmt.Errorf("logs: %w", err)
+	}
+	if c.Logs.Level != "" && !contains(LogLevels, c.Logs.Level) {
+		return fmt.Errorf("logs: invalid level: %s", c.Logs.Level)
+	}
 	return nil
 }
 
-// LoadConfig reads configuration from a YAML file (if it exists).
-// If the file is not found, returns an empty Config without error.
-func LoadConfig(path string) (*Config, error) {
-	cfg := &Config{
-		Telemetry: NewTelemetryConfig(),
+// validate checks the settings of a signal exported by one of exporters
+func (s *SignalConfig) validate(exporters []string) error {
+	if s.Exporter != "" && !contains(exporters, s.Exporter) {
+		return exporterTypeError(s.Exporter)
+	}
+	if s.ExportInterval < 0 {
+		return fmt.Errorf("export interval must not be negative, got: %d", s.ExportInterval)
 	}
+	return validateTransport(s.Compression, s.Timeout, s.TLS)
+}
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return cfg, nil
-		}
-		return nil, err
+// exporterTypeError reports an unsupported exporter type, pointing metrics-only exporters to
+// the metrics settings
+func exporterTypeError(exporter string) error {
+	if contains(MetricExporterTypes, exporter) {
+		return fmt.Errorf("invalid exporter type: %s (it only exports metrics; set metrics.exporter)", exporter)
 	}
+	return fmt.Errorf("invalid exporter type: %s", exporter)
+}
+
+// validateTransport checks the OTLP transport settings
+func validateTransport(compression string, timeout int, tls TLSConfig) error {
+	if compression != "" && !contains(Compressions, compression) {
+		return fmt.Errorf("invalid compression: %s", compression)
+	}
+	if timeout < 0 {
+		return fmt.Errorf("timeout must not be negative, got: %d", timeout)
+	}
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		return fmt.Errorf("tls: certFile and keyFile must be set together")
+	}
+	return nil
+}
 
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+// TracesExporter returns the exporter configuration of traces
+func (c *TelemetryConfig) TracesExporter() Exporter {
+	return c.exporter(&c.Tracing.SignalConfig, DefaultTraceInterval)
+}
+
+// MetricsExporter returns the exporter configuration of metrics
+func (c *TelemetryConfig) MetricsExporter() Exporter {
+	e := c.exporter(&c.Metrics.SignalConfig, DefaultMetricInterval)
+	e.Prometheus = PrometheusConfig{Listen: DefaultPrometheusListen, Textfile: DefaultPrometheusTextfile}
+	if c.Metrics.Prometheus.Listen != "" {
+		e.Prometheus.Listen = c.Metrics.Prometheus.Listen
+	}
+	if c.Metrics.Prometheus.Textfile != "" {
+		e.Prometheus.Textfile = c.Metrics.Prometheus.Textfile
 	}
+	return e
+}
+
+// LogsExporter returns the exporter configuration of logs
+func (c *TelemetryConfig) LogsExporter() Exporter {
+	return c.exporter(&c.Logs.SignalConfig, DefaultLogInterval)
+}
+
+// LogLevel returns the minimum level of the exported logs
+func (c *TelemetryConfig) LogLevel() string {
+	if c.Logs.Level != "" {
+		return c.Logs.Level
+	}
+	return DefaultLogLevel
+}
+
+// TraceSampleRate returns the trace sampling rate
+func (c *TelemetryConfig) TraceSampleRate() float64 {
+	if c.Tracing.SampleRate != nil {
+		return *c.Tracing.SampleRate
+	}
+	return c.SampleRate
+}
 
-	// Ensure telemetry config is not nil
-	if cfg.Telemetry == nil {
-		cfg.Telemetry = NewTelemetryConfig()
+// exporter resolves the settings of a signal against those of the telemetry config
+func (c *TelemetryConfig) exporter(s *SignalConfig, interval time.Duration) Exporter {
+	e := Exporter{
+		Type:     c.ExporterType,
+		Endpoint: c.OTLPEndpoint,
+		Insecure: c.Insecure,
+		Headers:  make(map[string]string, len(c.Headers)+len(s.Headers)),
+		Interval: interval,
 	}
+	for k, v := range c.Headers {
+		e.Headers[k] = v
+	}
+	for k, v := range s.Headers {
+		e.Headers[k] = v
+	}
+	if s.Exporter != "" {
+		e.Type = s.Exporter
+	}
+	if s.Endpoint != "" {
+		e.Endpoint = s.Endpoint
+	}
+	// Without an endpoint, send to the local collector's port of the protocol
+	if e.Endpoint == "" {
+		e.Endpoint = DefaultOTLPEndpoint
+		if e.Type == "otlphttp" {
+			e.Endpoint = DefaultOTLPHTTPEndpoint
+		}
+	}
+	if s.Insecure != nil {
+		e.Insecure = *s.Insecure
+	}
+	if s.ExportInterval > 0 {
+		e.Interval = time.Duration(s.ExportInterval) * time.Second
+	}
+	e.URLPath = s.URLPath
+	e.Compression = c.Compression
+	if s.Compression != "" {
+		e.Compression = s.Compression
+	}
+	timeout := c.Timeout
+	if s.Timeout > 0 {
+		timeout = s.Timeout
+	}
+	e.Timeout = time.Duration(timeout) * time.Second
+	e.TLS = c.TLS
+	if s.TLS.CAFile != "" {
+		e.TLS.CAFile = s.TLS.CAFile
+	}
+	if s.TLS.CertFile != "" {
+		e.TLS.CertFile, e.TLS.KeyFile = s.TLS.CertFile, s.TLS.KeyFile
+	}
+	e.FilePath = DefaultFilePath
+	if c.FilePath != "" {
+		e.FilePath = c.FilePath
+	}
+	if s.FilePath != "" {
+		e.FilePath = s.FilePath
+	}
+	if (s.Enabled != nil && !*s.Enabled) || e.Type == "" {
+		e.Type = "none"
+	}
+	return e
+}
 
-	return cfg, nil
+// LoadConfig reads configuration from a YAML file (if it exists).
+// If the file is not found, returns an empty Config without error.
+func LoadConfig(path string) (*Config, error) {
+	return LoadFiles(path)
 }
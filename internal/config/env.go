@@ -0,0 +1,319 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the environment variables holding valet settings
+const EnvPrefix = "VALET_"
+
+// ApplyEnv overrides the configuration with the settings found in the environment.
+// The standard OpenTelemetry variables OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_EXPORTER_OTLP_HEADERS,
+// OTEL_EXPORTER_OTLP_COMPRESSION, OTEL_EXPORTER_OTLP_TIMEOUT, the certificate variables,
+// OTEL_EXPORTER_PROMETHEUS_HOST and OTEL_EXPORTER_PROMETHEUS_PORT, OTEL_SERVICE_NAME and OTEL_TRACES_SAMPLER_ARG, and their per-signal variants, are read
+// first, so that the more specific VALET_TELEMETRY_* variables win. lookup is usually
+// os.LookupEnv.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	if c.Telemetry == nil {
+		c.Telemetry = NewTelemetryConfig()
+	}
+	e := &envReader{lookup: lookup, cfg: c}
+	t := c.Telemetry
+
+	// OpenTelemetry standard variables
+	if v, ok := e.get("OTEL_EXPORTER_OTLP_ENDPOINT"); ok {
+		t.OTLPEndpoint, t.Insecure = otlpEndpoint(v, t.Insecure)
+		c.SetOrigin("telemetry.otlpEndpoint", "env OTEL_EXPORTER_OTLP_ENDPOINT")
+		if strings.Contains(v, "://") {
+			c.SetOrigin("telemetry.insecure", "env OTEL_EXPORTER_OTLP_ENDPOINT")
+		}
+	}
+	e.headers("OTEL_EXPORTER_OTLP_HEADERS", "telemetry.headers", &t.Headers)
+	e.otelTransport("OTEL_EXPORTER_OTLP_", "telemetry", &t.Compression, &t.Timeout, &t.TLS)
+	e.string("OTEL_SERVICE_NAME", "telemetry.serviceName", &t.ServiceName)
+	e.float("OTEL_TRACES_SAMPLER_ARG", "telemetry.sampleRate", &t.SampleRate)
+	e.otelSignal("TRACES", "telemetry.tracing", &t.Tracing.SignalConfig)
+	e.otelSignal("METRICS", "telemetry.metrics", &t.Metrics.SignalConfig)
+	e.otelSignal("LOGS", "telemetry.logs", &t.Logs.SignalConfig)
+	e.otelPrometheus("telemetry.metrics.prometheus.listen", &t.Metrics.Prometheus.Listen)
+
+	// valet settings
+	e.string(EnvPrefix+"CONTEXT", "context", &c.Context)
+	e.string(EnvPrefix+"VALUES", "values", &c.Values)
+	e.string(EnvPrefix+"OVERRIDES", "overrides", &c.Overrides)
+	e.string(EnvPrefix+"OUTPUT", "output", &c.Output)
+	e.string(EnvPrefix+"FORMAT", "format", &c.Format)
+	e.string(EnvPrefix+"SCHEMA_OVERLAY", "schemaOverlay", &c.SchemaOverlay)
+	e.string(EnvPrefix+"STRICT", "strict", &c.Strict)
+	e.string(EnvPrefix+"DRAFT", "draft", &c.Draft)
+	e.bool(EnvPrefix+"CACHE", "cache", &c.Cache)
+	e.bool(EnvPrefix+"DEBUG", "debug", &c.Debug)
+
+	// Telemetry settings
+	e.bool(EnvPrefix+"TELEMETRY_ENABLED", "telemetry.enabled", &t.Enabled)
+	e.string(EnvPrefix+"TELEMETRY_SERVICE_NAME", "telemetry.serviceName", &t.ServiceName)
+	e.string(EnvPrefix+"TELEMETRY_EXPORTER", "telemetry.exporterType", &t.ExporterType)
+	e.string(EnvPrefix+"TELEMETRY_ENDPOINT", "telemetry.otlpEndpoint", &t.OTLPEndpoint)
+	e.bool(EnvPrefix+"TELEMETRY_INSECURE", "telemetry.insecure", &t.Insecure)
+	e.headers(EnvPrefix+"TELEMETRY_HEADERS", "telemetry.headers", &t.Headers)
+	e.float(EnvPrefix+"TELEMETRY_SAMPLE_RATE", "telemetry.sampleRate", &t.SampleRate)
+	e.transport(EnvPrefix+"TELEMETRY_", "telemetry", &t.Compression, &t.Timeout, &t.TLS)
+	e.string(EnvPrefix+"TELEMETRY_FILE_PATH", "telemetry.filePath", &t.FilePath)
+	e.signal(EnvPrefix+"TELEMETRY_TRACING_", "telemetry.tracing", &t.Tracing.SignalConfig)
+	e.optionalFloat(EnvPrefix+"TELEMETRY_TRACING_SAMPLE_RATE", "telemetry.tracing.sampleRate", &t.Tracing.SampleRate)
+	e.signal(EnvPrefix+"TELEMETRY_METRICS_", "telemetry.metrics", &t.Metrics.SignalConfig)
+	e.string(EnvPrefix+"TELEMETRY_METRICS_PROMETHEUS_LISTEN", "telemetry.metrics.prometheus.listen", &t.Metrics.Prometheus.Listen)
+	e.string(EnvPrefix+"TELEMETRY_METRICS_PROMETHEUS_TEXTFILE", "telemetry.metrics.prometheus.textfile", &t.Metrics.Prometheus.Textfile)
+	e.signal(EnvPrefix+"TELEMETRY_LOGS_", "telemetry.logs", &t.Logs.SignalConfig)
+	e.string(EnvPrefix+"TELEMETRY_LOGS_LEVEL", "telemetry.logs.level", &t.Logs.Level)
+
+	return e.err
+}
+
+// envReader reads typed environment variables into settings, recording their origin
+// and keeping the first error
+type envReader struct {
+	lookup func(string) (string, bool)
+	cfg    *Config
+	err    error
+}
+
+// get returns the value of a non-empty environment variable
+func (e *envReader) get(name string) (string, bool) {
+	v, ok := e.lookup(name)
+	if !ok || strings.TrimSpace(v) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(v), true
+}
+
+// fail records the first invalid variable
+func (e *envReader) fail(name, value, expected string) {
+	if e.err == nil {
+		e.err = fmt.Errorf("invalid %s %q: expected %s", name, value, expected)
+	}
+}
+
+func (e *envReader) string(name, key string, dst *string) {
+	if v, ok := e.get(name); ok {
+		*dst = v
+		e.cfg.SetOrigin(key, "env "+name)
+	}
+}
+
+func (e *envReader) bool(name, key string, dst *bool) {
+	if v, ok := e.get(name); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			e.fail(name, v, "a boolean")
+			return
+		}
+		*dst = b
+		e.cfg.SetOrigin(key, "env "+name)
+	}
+}
+
+func (e *envReader) optionalBool(name, key string, dst **bool) {
+	if v, ok := e.get(name); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			e.fail(name, v, "a boolean")
+			return
+		}
+		*dst = &b
+		e.cfg.SetOrigin(key, "env "+name)
+	}
+}
+
+func (e *envReader) int(name, key string, dst *int) {
+	if v, ok := e.get(name); ok {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			e.fail(name, v, "an integer")
+			return
+		}
+		*dst = i
+		e.cfg.SetOrigin(key, "env "+name)
+	}
+}
+
+func (e *envReader) float(name, key string, dst *float64) {
+	if v, ok := e.get(name); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			e.fail(name, v, "a number")
+			return
+		}
+		*dst = f
+		e.cfg.SetOrigin(key, "env "+name)
+	}
+}
+
+func (e *envReader) optionalFloat(name, key string, dst **float64) {
+	if v, ok := e.get(name); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			e.fail(name, v, "a number")
+			return
+		}
+		*dst = &f
+		e.cfg.SetOrigin(key, "env "+name)
+	}
+}
+
+// signal reads the exporter settings of a signal from the variables starting with prefix
+func (e *envReader) signal(prefix, key string, s *SignalConfig) {
+	e.optionalBool(prefix+"ENABLED", key+".enabled", &s.Enabled)
+	e.string(prefix+"EXPORTER", key+".exporter", &s.Exporter)
+	e.string(prefix+"ENDPOINT", key+".endpoint", &s.Endpoint)
+	e.optionalBool(prefix+"INSECURE", key+".insecure", &s.Insecure)
+	e.headers(prefix+"HEADERS", key+".headers", &s.Headers)
+	e.int(prefix+"EXPORT_INTERVAL", key+".exportInterval", &s.ExportInterval)
+	e.string(prefix+"URL_PATH", key+".urlPath", &s.URLPath)
+	e.string(prefix+"FILE_PATH", key+".filePath", &s.FilePath)
+	e.transport(prefix, key, &s.Compression, &s.Timeout, &s.TLS)
+}
+
+// transport reads the OTLP transport settings from the variables starting with prefix:
+// COMPRESSION, TIMEOUT (seconds) and TLS_CA_FILE, TLS_CERT_FILE and TLS_KEY_FILE
+func (e *envReader) transport(prefix, key string, compression *string, timeout *int, tls *TLSConfig) {
+	e.string(prefix+"COMPRESSION", key+".compression", compression)
+	e.int(prefix+"TIMEOUT", key+".timeout", timeout)
+	e.string(prefix+"TLS_CA_FILE", key+".tls.caFile", &tls.CAFile)
+	e.string(prefix+"TLS_CERT_FILE", key+".tls.certFile", &tls.CertFile)
+	e.string(prefix+"TLS_KEY_FILE", key+".tls.keyFile", &tls.KeyFile)
+}
+
+// otelTransport reads the OpenTelemetry transport variables starting with prefix:
+// COMPRESSION, TIMEOUT (milliseconds), CERTIFICATE, CLIENT_CERTIFICATE and CLIENT_KEY
+func (e *envReader) otelTransport(prefix, key string, compression *string, timeout *int, tls *TLSConfig) {
+	e.string(prefix+"COMPRESSION", key+".compression", compression)
+	name := prefix + "TIMEOUT"
+	if v, ok := e.get(name); ok {
+		ms, err := strconv.Atoi(v)
+		if err != nil {
+			e.fail(name, v, "an integer")
+		} else {
+			// Round up, so that a sub-second timeout does not mean the default
+			*timeout = (ms + 999) / 1000
+			e.cfg.SetOrigin(key+".timeout", "env "+name)
+		}
+	}
+	e.string(prefix+"CERTIFICATE", key+".tls.caFile", &tls.CAFile)
+	e.string(prefix+"CLIENT_CERTIFICATE", key+".tls.certFile", &tls.CertFile)
+	e.string(prefix+"CLIENT_KEY", key+".tls.keyFile", &tls.KeyFile)
+}
+
+// otelSignal reads the OpenTelemetry variables of a signal (TRACES, METRICS or LOGS):
+// OTEL_<SIGNAL>_EXPORTER and the OTEL_EXPORTER_OTLP_<SIGNAL>_* endpoint, headers and
+// transport variables. The path of a signal endpoint URL, as in
+// http://collector:4318/v1/traces, becomes the URL path of the otlphttp exporter.
+func (e *envReader) otelSignal(signal, key string, s *SignalConfig) {
+	name := "OTEL_" + signal + "_EXPORTER"
+	if v, ok := e.get(name); ok {
+		switch {
+		case v == "otlp", v == "none", v == "prometheus" && signal == "METRICS":
+			s.Exporter = v
+		case v == "console":
+			s.Exporter = "stdout"
+		case signal == "METRICS":
+			e.fail(name, v, "otlp, prometheus, console or none")
+			return
+		default:
+			e.fail(name, v, "otlp, console or none")
+			return
+		}
+		e.cfg.SetOrigin(key+".exporter", "env "+name)
+	}
+	name = "OTEL_EXPORTER_OTLP_" + signal + "_ENDPOINT"
+	if v, ok := e.get(name); ok {
+		endpoint, insecure := otlpEndpoint(v, false)
+		s.Endpoint = endpoint
+		e.cfg.SetOrigin(key+".endpoint", "env "+name)
+		if u, err := url.Parse(v); err == nil && u.Host != "" && strings.Trim(u.Path, "/") != "" {
+			s.URLPath = u.Path
+			e.cfg.SetOrigin(key+".urlPath", "env "+name)
+		}
+		if strings.Contains(v, "://") {
+			s.Insecure = &insecure
+			e.cfg.SetOrigin(key+".insecure", "env "+name)
+		}
+	}
+	e.headers("OTEL_EXPORTER_OTLP_"+signal+"_HEADERS", key+".headers", &s.Headers)
+	e.otelTransport("OTEL_EXPORTER_OTLP_"+signal+"_", key, &s.Compression, &s.Timeout, &s.TLS)
+}
+
+// otelPrometheus reads the listen address of the prometheus exporter from
+// OTEL_EXPORTER_PROMETHEUS_HOST and OTEL_EXPORTER_PROMETHEUS_PORT, either of which may be unset
+func (e *envReader) otelPrometheus(key string, dst *string) {
+	host, hostSet := e.get("OTEL_EXPORTER_PROMETHEUS_HOST")
+	port, portSet := e.get("OTEL_EXPORTER_PROMETHEUS_PORT")
+	if !hostSet && !portSet {
+		return
+	}
+	defaultHost, defaultPort, _ := net.SplitHostPort(DefaultPrometheusListen)
+	if !hostSet {
+		host = defaultHost
+	}
+	if !portSet {
+		port = defaultPort
+	} else if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		e.fail("OTEL_EXPORTER_PROMETHEUS_PORT", port, "a port number")
+		return
+	}
+	*dst = net.JoinHostPort(host, port)
+	e.cfg.SetOrigin(key, "env OTEL_EXPORTER_PROMETHEUS_HOST/PORT")
+}
+
+// headers reads a comma-separated list of key=value pairs, as in OTEL_EXPORTER_OTLP_HEADERS.
+// Values may be URL-encoded. The pairs are added to dst.
+func (e *envReader) headers(name, key string, dst *map[string]string) {
+	v, ok := e.get(name)
+	if !ok {
+		return
+	}
+	parsed := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		header, value, found := strings.Cut(pair, "=")
+		header = strings.TrimSpace(header)
+		if !found || header == "" {
+			e.fail(name, v, "comma-separated key=value pairs")
+			return
+		}
+		if decoded, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+			value = decoded
+		}
+		parsed[header] = strings.TrimSpace(value)
+	}
+	if *dst == nil {
+		*dst = make(map[string]string, len(parsed))
+	}
+	for k, val := range parsed {
+		(*dst)[k] = val
+		e.cfg.SetOrigin(key+"."+k, "env "+name)
+	}
+}
+
+// otlpEndpoint converts an OTLP endpoint URL, as in OTEL_EXPORTER_OTLP_ENDPOINT, into the
+// host:port valet dials. An http:// scheme makes the connection insecure and https:// secure;
+// without a scheme the endpoint is used as is.
+func otlpEndpoint(v string, insecure bool) (string, bool) {
+	u, err := url.Parse(v)
+	if err != nil || u.Host == "" {
+		return v, insecure
+	}
+	switch u.Scheme {
+	case "http":
+		insecure = true
+	case "https":
+		insecure = false
+	}
+	return u.Host, insecure
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FileName is the name of the config file looked up in the context directory and its parents
+const FileName = ".valet.yaml"
+
+// OriginDefault is the origin of settings that were not set by any source
+const OriginDefault = "default"
+
+// SetOrigin records where the setting with the given dotted YAML key (e.g. "telemetry.enabled") came from
+func (c *Config) SetOrigin(key, origin string) {
+	if c.Origins == nil {
+		c.Origins = make(map[string]string)
+	}
+	c.Origins[key] = origin
+}
+
+// Origin returns where the setting with the given dotted YAML key came from
+func (c *Config) Origin(key string) string {
+	if origin, ok := c.Origins[key]; ok {
+		return origin
+	}
+	return OriginDefault
+}
+
+// DiscoverFiles returns the config files that apply to dir, lowest precedence first:
+// ~/.valet.yaml, then $XDG_CONFIG_HOME/valet/config.yaml (default: ~/.config/valet/config.yaml),
+// then the .valet.yaml of every directory from the repository root (the nearest directory
+// containing .git) down to dir. Outside of a repository only dir itself is searched.
+// Only existing files are returned.
+func DiscoverFiles(dir string) []string {
+	var candidates []string
+	home, homeErr := os.UserHomeDir()
+	if homeErr == nil {
+		candidates = append(candidates, filepath.Join(home, FileName))
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		candidates = append(candidates, filepath.Join(xdg, "valet", "config.yaml"))
+	} else if homeErr == nil {
+		candidates = append(candidates, filepath.Join(home, ".config", "valet", "config.yaml"))
+	}
+	candidates = append(candidates, projectFiles(dir)...)
+
+	// A file can be found twice, e.g. when the home directory is the repository root;
+	// it then applies with its highest precedence
+	var files []string
+	for i, f := range candidates {
+		if _, err := os.Stat(f); err != nil || containsAfter(candidates, i, f) {
+			continue
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// projectFiles returns the .valet.yaml paths from the repository root down to dir
+func projectFiles(dir string) []string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return []string{filepath.Join(dir, FileName)}
+	}
+	var dirs []string
+	for d := abs; ; d = filepath.Dir(d) {
+		dirs = append(dirs, d)
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			break
+		}
+		if parent := filepath.Dir(d); parent == d {
+			// Not in a repository
+			dirs = dirs[:1]
+			break
+		}
+	}
+	files := make([]string, 0, len(dirs))
+	for i := len(dirs) - 1; i >= 0; i-- {
+		files = append(files, filepath.Join(dirs[i], FileName))
+	}
+	return files
+}
+
+// containsAfter reports whether s occurs in list after index i
+func containsAfter(list []string, i int, s string) bool {
+	for _, v := range list[i+1:] {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadFiles reads the config files in order and merges them key by key, later files
+// overriding earlier ones. Missing files are skipped. The file every setting came from
+// is recorded as its origin.
+func LoadFiles(paths ...string) (*Config, error) {
+	merged := make(map[string]any)
+	origins := make(map[string]string)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		// Decode the layer on its own first, so that errors, including unknown keys,
+		// point into the right file and line
+		if err := yaml.UnmarshalStrict(data, &Config{}); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+		var layer map[string]any
+		if err := yaml.Unmarshal(data, &layer); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+		layer = stringKeys(layer).(map[string]any)
+		resolveChartContexts(layer, filepath.Dir(path))
+		mergeLayer(merged, layer, "", "file "+path, origins)
+	}
+
+	cfg := &Config{
+		Telemetry: NewTelemetryConfig(),
+	}
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	// Ensure telemetry config is not nil
+	if cfg.Telemetry == nil {
+		cfg.Telemetry = NewTelemetryConfig()
+	}
+	for key, origin := range origins {
+		cfg.SetOrigin(key, origin)
+	}
+	return cfg, nil
+}
+
+// resolveChartContexts makes the relative contexts of the charts section of a layer
+// relative to dir, the directory of the config file declaring them
+func resolveChartContexts(layer map[string]any, dir string) {
+	charts, _ := layer["charts"].([]any)
+	for _, entry := range charts {
+		chart, ok := entry.(map[string]any)
+		if !ok {
+			continue
+		}
+		if ctx, ok := chart["context"].(string); ok && ctx != "" && !filepath.IsAbs(ctx) {
+			chart["context"] = filepath.Join(dir, ctx)
+		}
+	}
+}
+
+// mergeLayer merges layer into dst, recording origin for every leaf setting below prefix
+func mergeLayer(dst, layer map[string]any, prefix, origin string, origins map[string]string) {
+	keys := make([]string, 0, len(layer))
+	for k := range layer {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		src, srcIsMap := layer[k].(map[string]any)
+		existing, dstIsMap := dst[k].(map[string]any)
+		switch {
+		case srcIsMap && dstIsMap:
+			mergeLayer(existing, src, key, origin, origins)
+		case srcIsMap:
+			copied := make(map[string]any)
+			mergeLayer(copied, src, key, origin, origins)
+			dst[k] = copied
+			if len(src) == 0 {
+				origins[key] = origin
+			}
+		default:
+			dst[k] = layer[k]
+			origins[key] = origin
+		}
+	}
+}
+
+// stringKeys converts the map[interface{}]interface{} values decoded by yaml.v2 into
+// map[string]any, recursively
+func stringKeys(v any) any {
+	switch x := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(x))
+		for k, val := range x {
+			m[fmt.Sprintf("%v", k)] = stringKeys(val)
+		}
+		return m
+	case map[string]any:
+		for k, val := range x {
+			x[k] = stringKeys(val)
+		}
+		return x
+	case []any:
+		for i, val := range x {
+			x[i] = stringKeys(val)
+		}
+	}
+	return v
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Supported values of the enumerated settings
+var (
+	// Formats are the output formats
+	Formats = []string{"json", "yaml", "typescript", "go"}
+	// StrictModes are the strict loading modes
+	StrictModes = []string{"off", "warn", "error"}
+	// Drafts are the JSON Schema drafts a generated schema can declare
+	Drafts = []string{"draft-04", "draft-06", "draft-07", "2019-09", "2020-12"}
+	// RuleTypes are the JSON Schema types a rule can set
+	RuleTypes = []string{"string", "integer", "number", "boolean", "object", "array", "null"}
+	// ExporterTypes are the telemetry exporters
+	ExporterTypes = []string{"none", "stdout", "otlp", "otlphttp", "file"}
+	// MetricExporterTypes are the metrics exporters: the telemetry exporters and prometheus
+	MetricExporterTypes = []string{"none", "stdout", "otlp", "otlphttp", "file", "prometheus"}
+	// LogLevels are the minimum levels of exported logs
+	LogLevels = []string{"debug", "info", "warn", "error"}
+	// Compressions are the compressions of OTLP requests
+	Compressions = []string{"gzip", "none"}
+)
+
+// Validate checks every setting of the configuration and returns all the problems found
+func (c *Config) Validate() error {
+	var errs []error
+	if err := oneOf("output format", c.Format, Formats); err != nil {
+		errs = append(errs, err)
+	}
+	if err := oneOf("strict mode", c.Strict, StrictModes); err != nil {
+		errs = append(errs, err)
+	}
+	if err := oneOf("schema draft", c.Draft, Drafts); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, validateRules(c.Rules)...)
+	for i, chart := range c.Charts {
+		if chart.Context == "" {
+			errs = append(errs, fmt.Errorf("chart %d has no context", i+1))
+			continue
+		}
+		if err := oneOf("schema draft", chart.Draft, Drafts); err != nil {
+			errs = append(errs, fmt.Errorf("chart %s: %w", chart.Context, err))
+		}
+		for _, err := range validateRules(chart.Rules) {
+			errs = append(errs, fmt.Errorf("chart %s: %w", chart.Context, err))
+		}
+	}
+	if c.Telemetry != nil {
+		if err := c.Telemetry.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("telemetry: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateRules returns the problems of rules without a path or with an unsupported type
+func validateRules(rules []Rule) []error {
+	var errs []error
+	for i, r := range rules {
+		if r.Path == "" {
+			errs = append(errs, fmt.Errorf("rule %d has no path", i+1))
+			continue
+		}
+		if r.Type != "" && !contains(RuleTypes, r.Type) {
+			errs = append(errs, fmt.Errorf("rule %s: unsupported type %q (expected %s)", r.Path, r.Type, alternatives(RuleTypes)))
+		}
+	}
+	return errs
+}
+
+// oneOf returns an error when value is set but not one of allowed
+func oneOf(setting, value string, allowed []string) error {
+	if value == "" || contains(allowed, value) {
+		return nil
+	}
+	return fmt.Errorf("unsupported %s %q (expected %s)", setting, value, alternatives(allowed))
+}
+
+// contains reports whether list holds s
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// alternatives renders values as "a, b or c"
+func alternatives(values []string) string {
+	if len(values) < 2 {
+		return strings.Join(values, "")
+	}
+	return strings.Join(values[:len(values)-1], ", ") + " or " + values[len(values)-1]
+}
@@ -0,0 +1,502 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlErrorLine extracts the line number from yaml.v3 error messages
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// cursorContext describes where in the values tree a position is located
+type cursorContext struct {
+	// Parent is the values path of the mapping containing the position ("[*]" for list items)
+	Parent []string
+	// Key is the key on the line, if any
+	Key string
+	// InValue reports whether the position is after the key's colon
+	InValue bool
+	// Prefix is the partially typed key or value at the position
+	Prefix string
+}
+
+// locate determines the values path at a byte offset of a line using indentation, so it
+// also works on documents that are incomplete while they are being typed.
+func locate(lines []string, line, offset int) cursorContext {
+	var ctx cursorContext
+	if line < 0 || line >= len(lines) {
+		return ctx
+	}
+	if offset > len(lines[line]) {
+		offset = len(lines[line])
+	}
+	text := lines[line][:offset]
+	indent := leadingSpaces(text)
+	content := strings.TrimSpace(text)
+
+	var inner []string
+	if strings.HasPrefix(content, "- ") || content == "-" {
+		inner = append(inner, "[*]")
+		content = strings.TrimSpace(strings.TrimPrefix(content, "-"))
+	}
+	if idx := strings.Index(content, ":"); idx >= 0 {
+		ctx.Key = strings.TrimSpace(content[:idx])
+		ctx.InValue = true
+		ctx.Prefix = strings.Trim(strings.TrimSpace(content[idx+1:]), `"'`)
+	} else {
+		ctx.Prefix = content
+	}
+
+	threshold := indent
+	var parent []string
+	for i := line - 1; i >= 0 && threshold > 0; i-- {
+		l := lines[i]
+		trimmed := strings.TrimSpace(l)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		ind := leadingSpaces(l)
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			item := strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
+			contentIndent := ind + len(trimmed) - len(item)
+			if key, value, ok := splitKey(item); ok && value == "" && contentIndent < threshold {
+				parent = append([]string{"[*]", key}, parent...)
+				threshold = ind
+				continue
+			}
+			if ind < threshold {
+				parent = append([]string{"[*]"}, parent...)
+				threshold = ind
+			}
+			continue
+		}
+		if ind >= threshold {
+			continue
+		}
+		if key, _, ok := splitKey(trimmed); ok {
+			parent = append([]string{key}, parent...)
+		}
+		threshold = ind
+	}
+	ctx.Parent = append(parent, inner...)
+	return ctx
+}
+
+// splitKey splits a "key: value" line. ok is false when the line holds no key.
+func splitKey(line string) (key, value string, ok bool) {
+	idx := strings.Index(line, ":")
+	if idx <= 0 {
+		return "", "", false
+	}
+	if idx+1 < len(line) && line[idx+1] != ' ' && line[idx+1] != '\t' {
+		return "", "", false
+	}
+	key = strings.Trim(strings.TrimSpace(line[:idx]), `"'`)
+	value = strings.TrimSpace(line[idx+1:])
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	if strings.HasPrefix(value, "#") {
+		value = ""
+	}
+	return key, value, true
+}
+
+// position returns the LSP position of a byte offset in a line. LSP counts characters
+// in UTF-16 code units.
+func position(lines []string, line, offset int) Position {
+	if line < 0 || line >= len(lines) {
+		return Position{Line: line, Character: offset}
+	}
+	text := lines[line]
+	if offset > len(text) {
+		offset = len(text)
+	}
+	character := 0
+	for _, r := range text[:offset] {
+		character += utf16.RuneLen(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+// byteOffset returns the byte offset in line of an LSP character position
+func byteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(line)
+}
+
+// runeOffset returns the byte offset in line of a column counted in runes, as yaml.v3
+// reports them
+func runeOffset(line string, column int) int {
+	n := 0
+	for i := range line {
+		if n == column {
+			return i
+		}
+		n++
+	}
+	return len(line)
+}
+
+// leadingSpaces counts the indentation of a line
+func leadingSpaces(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
+
+// lookup returns the schema node at path, or nil if the path is not described
+func lookup(schema map[string]any, path []string) map[string]any {
+	node := schema
+	for _, seg := range path {
+		if node == nil {
+			return nil
+		}
+		if seg == "[*]" {
+			node, _ = node["items"].(map[string]any)
+			continue
+		}
+		props, _ := node["properties"].(map[string]any)
+		node, _ = props[seg].(map[string]any)
+	}
+	return node
+}
+
+// schemaTypes returns the allowed types of a schema node
+func schemaTypes(node map[string]any) []string {
+	switch t := node["type"].(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, v := range t {
+			types = append(types, fmt.Sprint(v))
+		}
+		return types
+	}
+	return nil
+}
+
+// schemaEnum returns the enum values of a schema node
+func schemaEnum(node map[string]any) []any {
+	switch e := node["enum"].(type) {
+	case []any:
+		return e
+	case []string:
+		out := make([]any, len(e))
+		for i, v := range e {
+			out[i] = v
+		}
+		return out
+	}
+	return nil
+}
+
+// completions returns the completion items for a position
+func completions(schema map[string]any, lines []string, pos Position) []CompletionItem {
+	var ctx cursorContext
+	if pos.Line >= 0 && pos.Line < len(lines) {
+		ctx = locate(lines, pos.Line, byteOffset(lines[pos.Line], pos.Character))
+	}
+	items := []CompletionItem{}
+
+	if ctx.InValue {
+		node := lookup(schema, append(ctx.Parent, ctx.Key))
+		if node == nil {
+			return items
+		}
+		for _, v := range schemaEnum(node) {
+			label := fmt.Sprint(v)
+			items = append(items, CompletionItem{Label: label, Kind: completionKindValue, Detail: "enum"})
+		}
+		if len(items) == 0 && contains(schemaTypes(node), "boolean") {
+			for _, label := range []string{"true", "false"} {
+				items = append(items, CompletionItem{Label: label, Kind: completionKindValue, Detail: "boolean"})
+			}
+		}
+		return items
+	}
+
+	node := lookup(schema, ctx.Parent)
+	props, _ := node["properties"].(map[string]any)
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		if strings.HasPrefix(key, ctx.Prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		prop, _ := props[key].(map[string]any)
+		items = append(items, CompletionItem{
+			Label:         key,
+			Kind:          completionKindProperty,
+			Detail:        strings.Join(schemaTypes(prop), "|"),
+			Documentation: &MarkupContent{Kind: markupKindMarkdown, Value: describe(prop)},
+			InsertText:    key + ": ",
+		})
+	}
+	return items
+}
+
+// hover returns the hover information for the key on the line at pos
+func hover(schema map[string]any, lines []string, pos Position) *Hover {
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return nil
+	}
+	line := lines[pos.Line]
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return nil
+	}
+	ctx := locate(lines, pos.Line, colon+1)
+	if ctx.Key == "" {
+		return nil
+	}
+	path := append(ctx.Parent, ctx.Key)
+	node := lookup(schema, path)
+	if node == nil {
+		return nil
+	}
+	start := strings.Index(line, ctx.Key)
+	return &Hover{
+		Contents: MarkupContent{
+			Kind:  markupKindMarkdown,
+			Value: fmt.Sprintf("**%s**\n\n%s", displayPath(path), describe(node)),
+		},
+		Range: &Range{
+			Start: position(lines, pos.Line, start),
+			End:   position(lines, pos.Line, start+len(ctx.Key)),
+		},
+	}
+}
+
+// describe renders the type, default and description of a schema node as markdown
+func describe(node map[string]any) string {
+	var b strings.Builder
+	if types := schemaTypes(node); len(types) > 0 {
+		fmt.Fprintf(&b, "Type: `%s`", strings.Join(types, " | "))
+	}
+	if def, ok := node["default"]; ok {
+		if data, err := json.Marshal(def); err == nil && len(data) <= 200 {
+			fmt.Fprintf(&b, "\n\nDefault: `%s`", data)
+		}
+	}
+	if enum := schemaEnum(node); len(enum) > 0 {
+		values := make([]string, len(enum))
+		for i, v := range enum {
+			values[i] = fmt.Sprintf("`%v`", v)
+		}
+		fmt.Fprintf(&b, "\n\nAllowed: %s", strings.Join(values, ", "))
+	}
+	if desc, ok := node["description"].(string); ok && desc != "" {
+		fmt.Fprintf(&b, "\n\n%s", desc)
+	}
+	return b.String()
+}
+
+// diagnose validates a values document against schema
+func diagnose(schema map[string]any, text string) []Diagnostic {
+	diags := []Diagnostic{}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil {
+		line := 0
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			line--
+		}
+		return append(diags, Diagnostic{
+			Range:    Range{Start: Position{Line: line}, End: Position{Line: line + 1}},
+			Severity: severityError,
+			Source:   "valet",
+			Message:  err.Error(),
+		})
+	}
+	if len(doc.Content) == 0 {
+		return diags
+	}
+	return validateNode(schema, strings.Split(text, "\n"), doc.Content[0], nil, diags)
+}
+
+// validateNode checks node against schema and appends any problems to diags.
+// Required properties are not checked: values files are merged over the chart defaults.
+func validateNode(schema map[string]any, lines []string, node *yaml.Node, path []string, diags []Diagnostic) []Diagnostic {
+	if schema == nil || node == nil {
+		return diags
+	}
+	if node.Kind == yaml.AliasNode {
+		return validateNode(schema, lines, node.Alias, path, diags)
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 {
+		actual := nodeType(node)
+		if !typeAllowed(types, actual) {
+			diags = append(diags, nodeDiagnostic(lines, node, severityError,
+				fmt.Sprintf("%s: expected %s, got %s", displayPath(path), strings.Join(types, " or "), actual)))
+			return diags
+		}
+	}
+
+	if enum := schemaEnum(schema); len(enum) > 0 && node.Kind == yaml.ScalarNode {
+		found := false
+		value := scalarValue(node)
+		for _, v := range enum {
+			if fmt.Sprint(v) == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			diags = append(diags, nodeDiagnostic(lines, node, severityError,
+				fmt.Sprintf("%s: value %q is not one of the allowed values", displayPath(path), node.Value)))
+		}
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		props, _ := schema["properties"].(map[string]any)
+		closed := schema["additionalProperties"] == false
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			childPath := append(append([]string{}, path...), keyNode.Value)
+			prop, ok := props[keyNode.Value].(map[string]any)
+			if !ok {
+				// Free-form maps such as annotations declare no properties
+				if len(props) == 0 && !closed {
+					continue
+				}
+				severity := severityWarning
+				if closed {
+					severity = severityError
+				}
+				diags = append(diags, nodeDiagnostic(lines, keyNode, severity,
+					fmt.Sprintf("%s: unknown key, not declared in the chart's values", displayPath(childPath))))
+				continue
+			}
+			diags = validateNode(prop, lines, valueNode, childPath, diags)
+		}
+	case yaml.SequenceNode:
+		if items, ok := schema["items"].(map[string]any); ok && len(items) > 0 {
+			for _, item := range node.Content {
+				diags = validateNode(items, lines, item, append(append([]string{}, path...), "[*]"), diags)
+			}
+		}
+	}
+	return diags
+}
+
+// nodeType returns the JSON Schema type of a YAML node as the values loader reads it
+func nodeType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	if isYAML11Boolean(node) {
+		return "boolean"
+	}
+	switch node.ShortTag() {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	}
+	return "string"
+}
+
+// isYAML11Boolean reports whether node is a plain scalar such as yes or off, which YAML 1.2
+// reads as a string but the values loader, following YAML 1.1, reads as a boolean
+func isYAML11Boolean(node *yaml.Node) bool {
+	if node.Kind != yaml.ScalarNode || node.Style != 0 || node.ShortTag() != "!!str" {
+		return false
+	}
+	switch node.Value {
+	case "y", "Y", "yes", "Yes", "YES", "on", "On", "ON",
+		"n", "N", "no", "No", "NO", "off", "Off", "OFF":
+		return true
+	}
+	return false
+}
+
+// scalarValue returns the value of a scalar node as the values loader reads it, with
+// booleans spelled true or false
+func scalarValue(node *yaml.Node) string {
+	switch {
+	case isYAML11Boolean(node):
+		switch strings.ToLower(node.Value) {
+		case "y", "yes", "on":
+			return "true"
+		}
+		return "false"
+	case node.ShortTag() == "!!bool":
+		return strings.ToLower(node.Value)
+	}
+	return node.Value
+}
+
+// typeAllowed reports whether actual satisfies one of the allowed types
+func typeAllowed(allowed []string, actual string) bool {
+	for _, t := range allowed {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeDiagnostic builds a diagnostic spanning node
+func nodeDiagnostic(lines []string, node *yaml.Node, severity int, msg string) Diagnostic {
+	line, col := node.Line-1, node.Column-1
+	width := len([]rune(node.Value))
+	if node.Kind != yaml.ScalarNode || width == 0 {
+		width = 1
+	}
+	var text string
+	if line >= 0 && line < len(lines) {
+		text = lines[line]
+	}
+	return Diagnostic{
+		Range: Range{
+			Start: position(lines, line, runeOffset(text, col)),
+			End:   position(lines, line, runeOffset(text, col+width)),
+		},
+		Severity: severity,
+		Source:   "valet",
+		Message:  msg,
+	}
+}
+
+// displayPath renders a values path for messages
+func displayPath(path []string) string {
+	if len(path) == 0 {
+		return "(root)"
+	}
+	return strings.ReplaceAll(strings.Join(path, "."), ".[*]", "[*]")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
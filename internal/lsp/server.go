@@ -0,0 +1,207 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// SchemaProvider returns the JSON Schema that applies to the values document at path.
+// It returns a nil schema when the document does not belong to a chart.
+type SchemaProvider func(path string) (map[string]any, error)
+
+// Server is a Language Server Protocol server for values files
+type Server struct {
+	conn      *conn
+	schemaFor SchemaProvider
+	version   string
+	docs      map[string]string
+}
+
+// NewServer creates a server that reads requests from r and writes responses to w
+func NewServer(r io.Reader, w io.Writer, schemaFor SchemaProvider, version string) *Server {
+	return &Server{
+		conn:      newConn(r, w),
+		schemaFor: schemaFor,
+		version:   version,
+		docs:      make(map[string]string),
+	}
+}
+
+// Run serves requests until the client sends exit, the input is closed or ctx is canceled
+func (s *Server) Run(ctx context.Context) error {
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+		msg, err := s.conn.read()
+		if err != nil {
+			var rpcErr *responseError
+			if errors.As(err, &rpcErr) {
+				// The id of an unparsable request is unknown, which JSON-RPC reports as null
+				id := json.RawMessage("null")
+				if err := s.conn.write(&message{ID: &id, Error: rpcErr}); err != nil {
+					return err
+				}
+				continue
+			}
+			var frameErr *framingError
+			if errors.As(err, &frameErr) {
+				zap.L().Warn("Skipping malformed LSP message", zap.Error(err))
+				continue
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches a single request or notification
+func (s *Server) handle(msg *message) error {
+	switch msg.Method {
+	case "initialize":
+		return s.reply(msg, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   textDocumentSyncFull,
+				"hoverProvider":      true,
+				"completionProvider": map[string]any{"triggerCharacters": []string{":", " "}},
+			},
+			"serverInfo": map[string]any{"name": "valet", "version": s.version},
+		})
+	case "shutdown":
+		return s.reply(msg, nil)
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		s.docs[params.TextDocument.URI] = params.TextDocument.Text
+		return s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		// Full document sync: the last change holds the whole text
+		s.docs[params.TextDocument.URI] = params.ContentChanges[len(params.ContentChanges)-1].Text
+		return s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didSave":
+		// Saving values.yaml changes the inferred schema of every open document of the chart
+		for uri := range s.docs {
+			if err := s.publishDiagnostics(uri); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return s.replyError(msg, codeInvalidParams, err.Error())
+		}
+		schema, lines := s.document(params.TextDocument.URI)
+		if schema == nil {
+			return s.reply(msg, []CompletionItem{})
+		}
+		return s.reply(msg, completions(schema, lines, params.Position))
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return s.replyError(msg, codeInvalidParams, err.Error())
+		}
+		schema, lines := s.document(params.TextDocument.URI)
+		if schema == nil {
+			return s.reply(msg, nil)
+		}
+		if h := hover(schema, lines, params.Position); h != nil {
+			return s.reply(msg, h)
+		}
+		return s.reply(msg, nil)
+	}
+
+	// Unknown requests get an error; unknown notifications are ignored
+	if msg.ID != nil {
+		return s.replyError(msg, codeMethodNotFound, "method not found: "+msg.Method)
+	}
+	return nil
+}
+
+// document returns the schema and lines of an open document
+func (s *Server) document(uri string) (map[string]any, []string) {
+	text, ok := s.docs[uri]
+	if !ok {
+		return nil, nil
+	}
+	schema, err := s.schemaFor(uriToPath(uri))
+	if err != nil {
+		zap.L().Debug("Failed to infer schema for document", zap.String("uri", uri), zap.Error(err))
+		return nil, nil
+	}
+	return schema, strings.Split(text, "\n")
+}
+
+// publishDiagnostics validates an open document and sends the result to the client
+func (s *Server) publishDiagnostics(uri string) error {
+	diags := []Diagnostic{}
+	if schema, _ := s.document(uri); schema != nil {
+		diags = diagnose(schema, s.docs[uri])
+	}
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diags,
+	})
+}
+
+// reply sends the result of a request
+func (s *Server) reply(req *message, result any) error {
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	return s.conn.write(&message{ID: req.ID, Result: result})
+}
+
+// replyError sends an error response to a request
+func (s *Server) replyError(req *message, code int, msg string) error {
+	return s.conn.write(&message{ID: req.ID, Error: &responseError{Code: code, Message: msg}})
+}
+
+// notify sends a notification to the client
+func (s *Server) notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.conn.write(&message{Method: method, Params: data})
+}
+
+// uriToPath converts a file:// URI to a local path
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
@@ -0,0 +1,323 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSchema mirrors what valet infers for a small chart
+var testSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"replicaCount": map[string]any{"type": "integer", "default": 1},
+		"image": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"repository": map[string]any{"type": "string", "default": "nginx"},
+				"pullPolicy": map[string]any{
+					"type":        "string",
+					"default":     "IfNotPresent",
+					"enum":        []any{"Always", "IfNotPresent", "Never"},
+					"description": "Image pull policy",
+				},
+			},
+		},
+		"ingress": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"enabled": map[string]any{"type": "boolean", "default": false},
+				"hosts": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"host": map[string]any{"type": "string"},
+						},
+					},
+				},
+			},
+		},
+		"podAnnotations": map[string]any{"type": "object", "properties": map[string]any{}},
+	},
+}
+
+// testClient drives a Server over in-memory pipes
+type testClient struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *conn
+	nextID int
+	done   chan error
+}
+
+func newTestClient(t *testing.T) *testClient {
+	clientToServer, serverIn := io.Pipe()
+	serverOut, clientFromServer := io.Pipe()
+	server := NewServer(clientToServer, clientFromServer, func(path string) (map[string]any, error) {
+		if strings.HasSuffix(path, "other.yaml") {
+			return nil, nil
+		}
+		return testSchema, nil
+	}, "test")
+
+	c := &testClient{t: t, in: serverIn, out: newConn(serverOut, io.Discard), done: make(chan error, 1)}
+	go func() { c.done <- server.Run(context.Background()) }()
+	t.Cleanup(func() {
+		c.send("exit", nil, false)
+		select {
+		case err := <-c.done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Error("server did not exit")
+		}
+	})
+	return c
+}
+
+func (c *testClient) send(method string, params any, request bool) {
+	msg := map[string]any{"jsonrpc": "2.0", "method": method}
+	if params != nil {
+		msg["params"] = params
+	}
+	if request {
+		c.nextID++
+		msg["id"] = c.nextID
+	}
+	body, err := json.Marshal(msg)
+	require.NoError(c.t, err)
+	_, err = fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	require.NoError(c.t, err)
+}
+
+func (c *testClient) receive(v any) *message {
+	msg, err := c.out.read()
+	require.NoError(c.t, err)
+	if v != nil {
+		var raw []byte
+		if msg.Method != "" {
+			raw = msg.Params
+		} else {
+			raw, err = json.Marshal(msg.Result)
+			require.NoError(c.t, err)
+		}
+		require.NoError(c.t, json.Unmarshal(raw, v))
+	}
+	return msg
+}
+
+func (c *testClient) open(uri, text string) publishDiagnosticsParams {
+	c.send("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "yaml", "version": 1, "text": text},
+	}, false)
+	var diags publishDiagnosticsParams
+	msg := c.receive(&diags)
+	require.Equal(c.t, "textDocument/publishDiagnostics", msg.Method)
+	return diags
+}
+
+func TestServer_Initialize(t *testing.T) {
+	c := newTestClient(t)
+	c.send("initialize", map[string]any{"capabilities": map[string]any{}}, true)
+
+	var result struct {
+		Capabilities map[string]any `json:"capabilities"`
+		ServerInfo   map[string]any `json:"serverInfo"`
+	}
+	c.receive(&result)
+	assert.Equal(t, float64(textDocumentSyncFull), result.Capabilities["textDocumentSync"])
+	assert.Equal(t, true, result.Capabilities["hoverProvider"])
+	assert.Equal(t, "valet", result.ServerInfo["name"])
+
+	c.send("shutdown", nil, true)
+	msg := c.receive(nil)
+	assert.Nil(t, msg.Error)
+}
+
+func TestServer_UnknownMethod(t *testing.T) {
+	c := newTestClient(t)
+	c.send("workspace/symbol", map[string]any{}, true)
+	msg := c.receive(nil)
+	require.NotNil(t, msg.Error)
+	assert.Equal(t, codeMethodNotFound, msg.Error.Code)
+}
+
+func TestServer_Diagnostics(t *testing.T) {
+	c := newTestClient(t)
+	doc := "replicaCount: three\n" +
+		"image:\n" +
+		"  pullPolicy: Sometimes\n" +
+		"  tag: v1\n" +
+		"ingress:\n" +
+		"  hosts:\n" +
+		"    - host: 42\n" +
+		"podAnnotations:\n" +
+		"  free.form/key: value\n"
+	diags := c.open("file:///chart/values-prod.yaml", doc)
+
+	require.Len(t, diags.Diagnostics, 4)
+	messages := make(map[int]Diagnostic)
+	for _, d := range diags.Diagnostics {
+		messages[d.Range.Start.Line] = d
+	}
+	assert.Contains(t, messages[0].Message, "replicaCount: expected integer, got string")
+	assert.Equal(t, severityError, messages[0].Severity)
+	assert.Equal(t, 14, messages[0].Range.Start.Character)
+	assert.Contains(t, messages[2].Message, `value "Sometimes" is not one of the allowed values`)
+	assert.Contains(t, messages[3].Message, "image.tag: unknown key")
+	assert.Equal(t, severityWarning, messages[3].Severity)
+	assert.Contains(t, messages[6].Message, "ingress.hosts[*].host: expected string, got integer")
+}
+
+// Values are loaded following YAML 1.1, which reads yes, no, on and off as booleans
+func TestServer_DiagnosticsYAML11Booleans(t *testing.T) {
+	c := newTestClient(t)
+	for _, value := range []string{"yes", "No", "on", "OFF"} {
+		diags := c.open("file:///chart/values-prod.yaml", "ingress:\n  enabled: "+value+"\n")
+		assert.Empty(t, diags.Diagnostics, "expected %s to be a boolean", value)
+	}
+
+	diags := c.open("file:///chart/values-prod.yaml", "replicaCount: on\ningress:\n  enabled: \"yes\"\n")
+	require.Len(t, diags.Diagnostics, 2)
+	assert.Contains(t, diags.Diagnostics[0].Message, "replicaCount: expected integer, got boolean")
+	assert.Contains(t, diags.Diagnostics[1].Message, "ingress.enabled: expected boolean, got string")
+}
+
+// Diagnostic ranges count characters in UTF-16 code units, as LSP positions do
+func TestServer_DiagnosticsUTF16(t *testing.T) {
+	c := newTestClient(t)
+	diags := c.open("file:///chart/values-prod.yaml", "image: {repository: \"🚀\", pullPolicy: Sometimes}\n")
+	require.Len(t, diags.Diagnostics, 1)
+	assert.Equal(t, Position{Line: 0, Character: 38}, diags.Diagnostics[0].Range.Start)
+	assert.Equal(t, Position{Line: 0, Character: 47}, diags.Diagnostics[0].Range.End)
+}
+
+func TestServer_DiagnosticsSyntaxError(t *testing.T) {
+	c := newTestClient(t)
+	diags := c.open("file:///chart/values.yaml", "image:\n  repository: [nginx\n")
+	require.Len(t, diags.Diagnostics, 1)
+	assert.Equal(t, severityError, diags.Diagnostics[0].Severity)
+}
+
+func TestServer_NoSchema(t *testing.T) {
+	c := newTestClient(t)
+	diags := c.open("file:///elsewhere/other.yaml", "replicaCount: three\n")
+	assert.Empty(t, diags.Diagnostics)
+}
+
+func TestServer_Completion(t *testing.T) {
+	c := newTestClient(t)
+	doc := "image:\n  pu\ningress:\n  hosts:\n    - h\n"
+	c.open("file:///chart/values.yaml", doc)
+
+	complete := func(line, character int) []CompletionItem {
+		c.send("textDocument/completion", map[string]any{
+			"textDocument": map[string]any{"uri": "file:///chart/values.yaml"},
+			"position":     map[string]any{"line": line, "character": character},
+		}, true)
+		var items []CompletionItem
+		c.receive(&items)
+		return items
+	}
+
+	items := complete(1, 4)
+	require.Len(t, items, 1)
+	assert.Equal(t, "pullPolicy", items[0].Label)
+	assert.Equal(t, "string", items[0].Detail)
+	assert.Contains(t, items[0].Documentation.Value, "Image pull policy")
+
+	items = complete(4, 7)
+	require.Len(t, items, 1)
+	assert.Equal(t, "host", items[0].Label)
+
+	items = complete(0, 0)
+	labels := make([]string, len(items))
+	for i, item := range items {
+		labels[i] = item.Label
+	}
+	assert.Equal(t, []string{"image", "ingress", "podAnnotations", "replicaCount"}, labels)
+}
+
+func TestServer_CompletionValues(t *testing.T) {
+	c := newTestClient(t)
+	doc := "image:\n  pullPolicy: \ningress:\n  enabled: \n"
+	c.open("file:///chart/values.yaml", doc)
+
+	c.send("textDocument/completion", map[string]any{
+		"textDocument": map[string]any{"uri": "file:///chart/values.yaml"},
+		"position":     map[string]any{"line": 1, "character": 14},
+	}, true)
+	var items []CompletionItem
+	c.receive(&items)
+	require.Len(t, items, 3)
+	assert.Equal(t, "Always", items[0].Label)
+
+	c.send("textDocument/completion", map[string]any{
+		"textDocument": map[string]any{"uri": "file:///chart/values.yaml"},
+		"position":     map[string]any{"line": 3, "character": 11},
+	}, true)
+	c.receive(&items)
+	require.Len(t, items, 2)
+	assert.Equal(t, "true", items[0].Label)
+}
+
+func TestServer_Hover(t *testing.T) {
+	c := newTestClient(t)
+	c.open("file:///chart/values.yaml", "image:\n  pullPolicy: Always\n")
+
+	c.send("textDocument/hover", map[string]any{
+		"textDocument": map[string]any{"uri": "file:///chart/values.yaml"},
+		"position":     map[string]any{"line": 1, "character": 4},
+	}, true)
+	var h Hover
+	c.receive(&h)
+	assert.Contains(t, h.Contents.Value, "**image.pullPolicy**")
+	assert.Contains(t, h.Contents.Value, "Type: `string`")
+	assert.Contains(t, h.Contents.Value, "Default: `\"IfNotPresent\"`")
+	assert.Contains(t, h.Contents.Value, "Image pull policy")
+	require.NotNil(t, h.Range)
+	assert.Equal(t, 2, h.Range.Start.Character)
+}
+
+func TestLocate(t *testing.T) {
+	lines := strings.Split("hosts:\n  - host: a\n    paths:\n      - pa\n- foo:\n    ba", "\n")
+
+	ctx := locate(lines, 3, 10)
+	assert.Equal(t, []string{"hosts", "[*]", "paths", "[*]"}, ctx.Parent)
+	assert.Equal(t, "pa", ctx.Prefix)
+	assert.False(t, ctx.InValue)
+
+	ctx = locate(lines, 5, 6)
+	assert.Equal(t, []string{"[*]", "foo"}, ctx.Parent)
+
+	ctx = locate(lines, 1, len(lines[1]))
+	assert.Equal(t, []string{"hosts", "[*]"}, ctx.Parent)
+	assert.Equal(t, "host", ctx.Key)
+	assert.True(t, ctx.InValue)
+	assert.Equal(t, "a", ctx.Prefix)
+}
+
+func TestPosition(t *testing.T) {
+	lines := []string{"name: café 🚀 x"}
+	// é is 2 bytes and 1 UTF-16 unit, 🚀 is 4 bytes and 2 UTF-16 units
+	x := strings.Index(lines[0], "x")
+	assert.Equal(t, Position{Line: 0, Character: 14}, position(lines, 0, x))
+	assert.Equal(t, x, byteOffset(lines[0], 14))
+	assert.Equal(t, x, runeOffset(lines[0], 13))
+	assert.Equal(t, len(lines[0]), byteOffset(lines[0], 100))
+}
+
+// Ensure the framing reader rejects malformed headers
+func TestConn_InvalidHeader(t *testing.T) {
+	c := newConn(bufio.NewReader(strings.NewReader("Content-Length: nope\r\n\r\n")), io.Discard)
+	_, err := c.read()
+	assert.Error(t, err)
+}
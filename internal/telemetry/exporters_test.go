@@ -0,0 +1,601 @@
+package telemetry
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mkm29/valet/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	collectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpRequest is a request received by the collector stand-in
+type otlpRequest struct {
+	path     string
+	header   http.Header
+	encoding string
+	body     []byte
+}
+
+// collector is an in-process stand-in for an OTLP/HTTP collector recording the requests it receives
+type collector struct {
+	mu       sync.Mutex
+	requests []otlpRequest
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.requests = append(c.requests, otlpRequest{
+		path:     r.URL.Path,
+		header:   r.Header.Clone(),
+		encoding: r.Header.Get("Content-Encoding"),
+		body:     data,
+	})
+	c.mu.Unlock()
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+// request returns the first request received on path
+func (c *collector) request(t *testing.T, path string) otlpRequest {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range c.requests {
+		if r.path == path {
+			return r
+		}
+	}
+	require.Failf(t, "no OTLP request", "collector received nothing on %s", path)
+	return otlpRequest{}
+}
+
+// spanNames decodes the names of the spans of an OTLP trace request
+func spanNames(t *testing.T, r otlpRequest) []string {
+	t.Helper()
+	var req collectortrace.ExportTraceServiceRequest
+	require.NoError(t, proto.Unmarshal(r.body, &req))
+	var names []string
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, s := range ss.GetSpans() {
+				names = append(names, s.GetName())
+			}
+		}
+	}
+	return names
+}
+
+// exportSpan initializes telemetry with cfg, records a span and flushes it on shutdown
+func exportSpan(t *testing.T, cfg *config.TelemetryConfig, name string) {
+	t.Helper()
+	ctx := context.Background()
+	tel, err := Initialize(ctx, cfg)
+	require.NoError(t, err)
+
+	_, span := tel.StartSpan(ctx, name)
+	span.End()
+	metrics, err := tel.NewCommandMetrics()
+	require.NoError(t, err)
+	metrics.RecordCommandExecution(ctx, "test", time.Millisecond, nil)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err = tel.Shutdown(shutdownCtx)
+	// Ignore sync errors in tests
+	if err != nil && !strings.Contains(err.Error(), "sync") {
+		assert.NoError(t, err)
+	}
+}
+
+func TestOTLPHTTPExporter(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	exportSpan(t, &config.TelemetryConfig{
+		Enabled:        true,
+		ServiceName:    "test-service",
+		ServiceVersion: "1.0.0",
+		ExporterType:   "otlphttp",
+		OTLPEndpoint:   strings.TrimPrefix(srv.URL, "http://"),
+		Insecure:       true,
+		Headers:        map[string]string{"X-Api-Key": "secret"},
+		Compression:    "gzip",
+		Timeout:        5,
+		SampleRate:     1.0,
+		Tracing: config.TracingConfig{
+			SignalConfig: config.SignalConfig{URLPath: "/custom/traces"},
+		},
+	}, "otlphttp-span")
+
+	traces := c.request(t, "/custom/traces")
+	assert.Equal(t, "secret", traces.header.Get("X-Api-Key"))
+	assert.Equal(t, "gzip", traces.encoding)
+	assert.Equal(t, "application/x-protobuf", traces.header.Get("Content-Type"))
+	assert.Contains(t, spanNames(t, traces), "otlphttp-span")
+
+	metrics := c.request(t, "/v1/metrics")
+	var req collectormetrics.ExportMetricsServiceRequest
+	require.NoError(t, proto.Unmarshal(metrics.body, &req))
+	assert.NotEmpty(t, req.GetResourceMetrics())
+}
+
+func TestOTLPHTTPExporter_TLS(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewTLSServer(c)
+	defer srv.Close()
+
+	// Trust the certificate of the test server
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, ca, 0600))
+
+	exportSpan(t, &config.TelemetryConfig{
+		Enabled:        true,
+		ServiceName:    "test-service",
+		ServiceVersion: "1.0.0",
+		ExporterType:   "none",
+		SampleRate:     1.0,
+		Tracing: config.TracingConfig{
+			SignalConfig: config.SignalConfig{
+				Exporter: "otlphttp",
+				Endpoint: strings.TrimPrefix(srv.URL, "https://"),
+				TLS:      config.TLSConfig{CAFile: caFile},
+			},
+		},
+	}, "tls-span")
+
+	assert.Contains(t, spanNames(t, c.request(t, "/v1/traces")), "tls-span")
+}
+
+func TestOTLPExporter_InvalidTLS(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("not a certificate"), 0600))
+
+	tests := []struct {
+		name    string
+		tls     config.TLSConfig
+		wantErr string
+	}{
+		{
+			name:    "missing CA file",
+			tls:     config.TLSConfig{CAFile: filepath.Join(dir, "missing.pem")},
+			wantErr: "failed to read CA file",
+		},
+		{
+			name:    "CA file without certificates",
+			tls:     config.TLSConfig{CAFile: empty},
+			wantErr: "no certificates found in CA file",
+		},
+		{
+			name:    "invalid client certificate",
+			tls:     config.TLSConfig{CertFile: empty, KeyFile: empty},
+			wantErr: "failed to load client certificate",
+		},
+	}
+
+	for _, tt := range tests {
+		for _, exporter := range []string{"otlp", "otlphttp"} {
+			t.Run(tt.name+" "+exporter, func(t *testing.T) {
+				_, err := Initialize(context.Background(), &config.TelemetryConfig{
+					Enabled:      true,
+					ServiceName:  "test-service",
+					ExporterType: exporter,
+					OTLPEndpoint: "localhost:4318",
+					TLS:          tt.tls,
+				})
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			})
+		}
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	cfg := &config.TelemetryConfig{
+		Enabled:        true,
+		ServiceName:    "test-service",
+		ServiceVersion: "1.0.0",
+		ExporterType:   "file",
+		FilePath:       path,
+		SampleRate:     1.0,
+	}
+	// Runs append to the file
+	exportSpan(t, cfg, "first-span")
+	exportSpan(t, cfg, "second-span")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var spans, metrics []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var req struct {
+			ResourceSpans []struct {
+				Resource struct {
+					Attributes []struct {
+						Key   string
+						Value map[string]any
+					}
+				}
+				ScopeSpans []struct {
+					Spans []struct {
+						TraceID string `json:"traceId"`
+						SpanID  string `json:"spanId"`
+						Name    string
+						Kind    int
+					}
+				}
+			}
+			ResourceMetrics []struct {
+				ScopeMetrics []struct {
+					Metrics []struct {
+						Name      string
+						Sum       map[string]any
+						Histogram map[string]any
+					}
+				}
+			}
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &req), "line is not JSON: %s", line)
+		for _, rs := range req.ResourceSpans {
+			assert.Contains(t, rs.Resource.Attributes, struct {
+				Key   string
+				Value map[string]any
+			}{"service.name", map[string]any{"stringValue": "test-service"}})
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					assert.Regexp(t, "^[0-9a-f]{32}$", s.TraceID, "trace ID must be hex")
+					assert.Regexp(t, "^[0-9a-f]{16}$", s.SpanID, "span ID must be hex")
+					assert.Equal(t, 1, s.Kind, "kind must be a number")
+					spans = append(spans, s.Name)
+				}
+			}
+		}
+		for _, rm := range req.ResourceMetrics {
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					switch m.Name {
+					case "valet.command.executions":
+						assert.NotNil(t, m.Sum)
+					case "valet.command.duration":
+						assert.NotNil(t, m.Histogram)
+					}
+					metrics = append(metrics, m.Name)
+				}
+			}
+		}
+	}
+	assert.Equal(t, []string{"first-span", "second-span"}, spans)
+	assert.Contains(t, metrics, "valet.command.executions")
+	assert.Contains(t, metrics, "valet.command.duration")
+}
+
+func TestFileExporter_Aggregations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.jsonl")
+	exporter, err := newFileMetricExporter(path)
+	require.NoError(t, err)
+	now := time.Now()
+	rm := &metricdata.ResourceMetrics{
+		Resource: resource.Empty(),
+		ScopeMetrics: []metricdata.ScopeMetrics{{
+			Metrics: []metricdata.Metrics{
+				{Name: "gauge", Data: metricdata.Gauge[float64]{
+					DataPoints: []metricdata.DataPoint[float64]{{Time: now, Value: 1.5}},
+				}},
+				{Name: "sum", Data: metricdata.Sum[int64]{
+					Temporality: metricdata.CumulativeTemporality,
+					IsMonotonic: true,
+					DataPoints:  []metricdata.DataPoint[int64]{{Time: now, Value: 3}},
+				}},
+				{Name: "histogram", Data: metricdata.Histogram[float64]{
+					Temporality: metricdata.CumulativeTemporality,
+					DataPoints: []metricdata.HistogramDataPoint[float64]{{
+						Time: now, Count: 1, Sum: 0.5, Bounds: []float64{1}, BucketCounts: []uint64{1, 0},
+					}},
+				}},
+				{Name: "exponential", Data: metricdata.ExponentialHistogram[int64]{
+					Temporality: metricdata.DeltaTemporality,
+					DataPoints: []metricdata.ExponentialHistogramDataPoint[int64]{{
+						Time: now, Count: 2, Sum: 5, Scale: 2,
+						PositiveBucket: metricdata.ExponentialBucket{Offset: 1, Counts: []uint64{2}},
+					}},
+				}},
+				{Name: "summary", Data: metricdata.Summary{
+					DataPoints: []metricdata.SummaryDataPoint{{
+						Time: now, Count: 4, Sum: 10,
+						QuantileValues: []metricdata.QuantileValue{{Quantile: 0.5, Value: 2}},
+					}},
+				}},
+				{Name: "unknown"},
+			},
+		}},
+	}
+
+	// Metrics of an unknown aggregation are reported, and the others still written
+	err = exporter.Export(context.Background(), rm)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "metric unknown has an unsupported aggregation")
+	require.NoError(t, exporter.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var req struct {
+		ResourceMetrics []struct {
+			ScopeMetrics []struct {
+				Metrics []map[string]any
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(data, &req), "line is not JSON: %s", data)
+	require.Len(t, req.ResourceMetrics, 1)
+	require.Len(t, req.ResourceMetrics[0].ScopeMetrics, 1)
+	written := make(map[string]map[string]any)
+	for _, m := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		written[m["name"].(string)] = m
+	}
+	assert.Len(t, written, 5)
+	assert.Contains(t, written["gauge"], "gauge")
+	assert.Contains(t, written["sum"], "sum")
+	assert.Contains(t, written["histogram"], "histogram")
+	assert.Contains(t, written["exponential"], "exponentialHistogram")
+	require.Contains(t, written["summary"], "summary")
+	point := written["summary"]["summary"].(map[string]any)["dataPoints"].([]any)[0].(map[string]any)
+	assert.Equal(t, []any{map[string]any{"quantile": 0.5, "value": 2.0}}, point["quantileValues"])
+}
+
+func TestFileExporter_InvalidPath(t *testing.T) {
+	_, err := Initialize(context.Background(), &config.TelemetryConfig{
+		Enabled:      true,
+		ServiceName:  "test-service",
+		ExporterType: "file",
+		FilePath:     filepath.Join(t.TempDir(), "missing", "telemetry.jsonl"),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to open telemetry file")
+}
+
+func TestFileExporter_Logs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	ctx := context.Background()
+	tel, err := Initialize(ctx, &config.TelemetryConfig{
+		Enabled:        true,
+		ServiceName:    "test-service",
+		ServiceVersion: "1.0.0",
+		ExporterType:   "file",
+		FilePath:       path,
+		SampleRate:     1.0,
+		Metrics:        config.MetricsConfig{SignalConfig: config.SignalConfig{Exporter: "none"}},
+		Logs:           config.LogsConfig{Level: "warn"},
+	})
+	require.NoError(t, err)
+
+	spanCtx, span := tel.StartSpan(ctx, "logging-span")
+	tel.Logger().Info(spanCtx, "below the level")
+	tel.Logger().Warn(spanCtx, "schema field skipped", zap.String("field", "image.tag"))
+	span.End()
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err = tel.Shutdown(shutdownCtx)
+	// Ignore sync errors in tests
+	if err != nil && !strings.Contains(err.Error(), "sync") {
+		assert.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	type logRecord struct {
+		SeverityNumber int
+		SeverityText   string
+		Body           map[string]any
+		Attributes     []struct {
+			Key   string
+			Value map[string]any
+		}
+		TraceID string `json:"traceId"`
+		SpanID  string `json:"spanId"`
+	}
+	var records []logRecord
+	var traceID string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []struct {
+						TraceID string `json:"traceId"`
+					}
+				}
+			}
+			ResourceLogs []struct {
+				ScopeLogs []struct {
+					LogRecords []logRecord
+				}
+			}
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &req), "line is not JSON: %s", line)
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					traceID = s.TraceID
+				}
+			}
+		}
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				records = append(records, sl.LogRecords...)
+			}
+		}
+	}
+
+	// Only the warning reaches the logs pipeline
+	require.Len(t, records, 1)
+	r := records[0]
+	assert.Equal(t, map[string]any{"stringValue": "schema field skipped"}, r.Body)
+	assert.Equal(t, 13, r.SeverityNumber)
+	assert.Equal(t, "warn", r.SeverityText)
+	assert.Equal(t, traceID, r.TraceID, "log record must carry the trace of its span")
+	assert.Regexp(t, "^[0-9a-f]{16}$", r.SpanID, "span ID must be hex")
+	assert.Contains(t, r.Attributes, struct {
+		Key   string
+		Value map[string]any
+	}{"field", map[string]any{"stringValue": "image.tag"}})
+}
+
+func TestOTLPHTTPExporter_Logs(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	ctx := context.Background()
+	tel, err := Initialize(ctx, &config.TelemetryConfig{
+		Enabled:      true,
+		ServiceName:  "test-service",
+		ExporterType: "otlphttp",
+		OTLPEndpoint: strings.TrimPrefix(srv.URL, "http://"),
+		Insecure:     true,
+		SampleRate:   1.0,
+	})
+	require.NoError(t, err)
+	spanCtx, span := tel.StartSpan(ctx, "logging-span")
+	tel.Logger().Info(spanCtx, "generating schema")
+	span.End()
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_ = tel.Shutdown(shutdownCtx)
+
+	var req collectorlogs.ExportLogsServiceRequest
+	require.NoError(t, proto.Unmarshal(c.request(t, "/v1/logs").body, &req))
+	records := req.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()
+	require.Len(t, records, 1)
+	assert.Equal(t, "generating schema", records[0].GetBody().GetStringValue())
+	traceID := span.SpanContext().TraceID()
+	assert.Equal(t, traceID[:], records[0].GetTraceId())
+}
+
+func TestLogsExporter_None(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	ctx := context.Background()
+	tel, err := Initialize(ctx, &config.TelemetryConfig{
+		Enabled:      true,
+		ServiceName:  "test-service",
+		ExporterType: "otlphttp",
+		OTLPEndpoint: strings.TrimPrefix(srv.URL, "http://"),
+		Insecure:     true,
+		SampleRate:   1.0,
+		Logs:         config.LogsConfig{SignalConfig: config.SignalConfig{Exporter: "none"}},
+	})
+	require.NoError(t, err)
+	tel.Logger().Error(ctx, "not exported")
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_ = tel.Shutdown(shutdownCtx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range c.requests {
+		assert.NotEqual(t, "/v1/logs", r.path, "logs must not be exported")
+	}
+}
+
+// prometheusConfig returns a telemetry config exporting metrics to Prometheus
+func prometheusConfig(prom config.PrometheusConfig) *config.TelemetryConfig {
+	return &config.TelemetryConfig{
+		Enabled:        true,
+		ServiceName:    "test-service",
+		ServiceVersion: "1.0.0",
+		ExporterType:   "none",
+		Metrics: config.MetricsConfig{
+			SignalConfig: config.SignalConfig{Exporter: "prometheus"},
+			Prometheus:   prom,
+		},
+	}
+}
+
+func TestPrometheusExporter_Textfile(t *testing.T) {
+	textfile := filepath.Join(t.TempDir(), "valet.prom")
+	exportSpan(t, prometheusConfig(config.PrometheusConfig{Textfile: textfile}), "prometheus-span")
+
+	data, err := os.ReadFile(textfile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# TYPE valet_command_executions_total counter")
+	assert.Contains(t, string(data), `valet_command_executions_total{command="test"`)
+	assert.Contains(t, string(data), "valet_command_duration_seconds_bucket")
+}
+
+func TestPrometheusExporter_Serve(t *testing.T) {
+	textfile := filepath.Join(t.TempDir(), "valet.prom")
+	ctx := context.Background()
+	tel, err := Initialize(ctx, prometheusConfig(config.PrometheusConfig{Listen: "127.0.0.1:0", Textfile: textfile}))
+	require.NoError(t, err)
+
+	addr, err := tel.ServeMetrics()
+	require.NoError(t, err)
+	require.NotEmpty(t, addr)
+	_, err = tel.ServeMetrics()
+	assert.Error(t, err, "expected serving twice to fail")
+
+	metrics, err := tel.NewCommandMetrics()
+	require.NoError(t, err)
+	metrics.RecordCommandExecution(ctx, "watch", time.Millisecond, nil)
+
+	resp, err := http.Get("http://" + addr + "/metrics")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `valet_command_executions_total{command="watch"`)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err = tel.Shutdown(shutdownCtx)
+	if err != nil && !strings.Contains(err.Error(), "sync") {
+		assert.NoError(t, err)
+	}
+	assert.NoFileExists(t, textfile, "served metrics must not be written to the textfile")
+
+	// Without the prometheus exporter there is nothing to serve
+	addr, err = (&Telemetry{}).ServeMetrics()
+	assert.NoError(t, err)
+	assert.Empty(t, addr)
+}
@@ -0,0 +1,499 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collectorlogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// The file exporter appends every export to a file as a line of OTLP JSON, the format the
+// collector's otlpjsonfile receiver replays. Each line is a single write to a file opened
+// for appending, so the signals can share a file and runs add to it.
+
+// newFileSpanExporter creates a span exporter appending to path
+func newFileSpanExporter(ctx context.Context, path string) (sdktrace.SpanExporter, error) {
+	return otlptrace.New(ctx, &fileTraceClient{path: path})
+}
+
+// fileTraceClient is an OTLP trace client writing to a file instead of a collector
+type fileTraceClient struct {
+	path string
+	file *os.File
+}
+
+func (c *fileTraceClient) Start(context.Context) error {
+	f, err := openTelemetryFile(c.path)
+	if err != nil {
+		return err
+	}
+	c.file = f
+	return nil
+}
+
+func (c *fileTraceClient) Stop(context.Context) error {
+	return c.file.Close()
+}
+
+func (c *fileTraceClient) UploadTraces(_ context.Context, spans []*tracepb.ResourceSpans) error {
+	return writeOTLPLine(c.file, &collectortracepb.ExportTraceServiceRequest{ResourceSpans: spans})
+}
+
+// fileMetricExporter is a metric exporter appending to a file
+type fileMetricExporter struct {
+	file *os.File
+}
+
+// newFileMetricExporter creates a metric exporter appending to path
+func newFileMetricExporter(path string) (sdkmetric.Exporter, error) {
+	f, err := openTelemetryFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileMetricExporter{file: f}, nil
+}
+
+func (e *fileMetricExporter) Temporality(k sdkmetric.InstrumentKind) metricdata.Temporality {
+	return sdkmetric.DefaultTemporalitySelector(k)
+}
+
+func (e *fileMetricExporter) Aggregation(k sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(k)
+}
+
+func (e *fileMetricExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	out, convErr := resourceMetrics(rm)
+	err := writeOTLPLine(e.file, &collectormetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{out},
+	})
+	return errors.Join(err, convErr)
+}
+
+func (e *fileMetricExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+func (e *fileMetricExporter) Shutdown(context.Context) error {
+	return e.file.Close()
+}
+
+// fileLogExporter is a log exporter appending to a file
+type fileLogExporter struct {
+	file *os.File
+}
+
+// newFileLogExporter creates a log exporter appending to path
+func newFileLogExporter(path string) (sdklog.Exporter, error) {
+	f, err := openTelemetryFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileLogExporter{file: f}, nil
+}
+
+func (e *fileLogExporter) Export(_ context.Context, records []sdklog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return writeOTLPLine(e.file, &collectorlogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{resourceLogs(records)},
+	})
+}
+
+func (e *fileLogExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+func (e *fileLogExporter) Shutdown(context.Context) error {
+	return e.file.Close()
+}
+
+// openTelemetryFile opens path for appending, creating it if needed
+func openTelemetryFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open telemetry file: %w", err)
+	}
+	return f, nil
+}
+
+// writeOTLPLine appends an export request to f as a line of OTLP JSON
+func writeOTLPLine(f *os.File, req proto.Message) error {
+	data, err := otlpJSON(req)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write telemetry file: %w", err)
+	}
+	return nil
+}
+
+// otlpJSON encodes an OTLP message as OTLP JSON: the proto3 JSON mapping with enums as
+// numbers and trace and span IDs as hex strings instead of base64
+func otlpJSON(m proto.Message) ([]byte, error) {
+	data, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OTLP JSON: %w", err)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to encode OTLP JSON: %w", err)
+	}
+	hexIDs(v)
+	return json.Marshal(v)
+}
+
+// hexIDs rewrites the base64 trace and span IDs found in a decoded JSON value as hex
+func hexIDs(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			switch k {
+			case "traceId", "spanId", "parentSpanId":
+				if s, ok := val.(string); ok {
+					if id, err := base64.StdEncoding.DecodeString(s); err == nil {
+						v[k] = hex.EncodeToString(id)
+					}
+				}
+			default:
+				hexIDs(val)
+			}
+		}
+	case []any:
+		for _, val := range v {
+			hexIDs(val)
+		}
+	}
+}
+
+// resourceMetrics converts metrics collected by the SDK into their OTLP message.
+// Exemplars are left out. Metrics of an unknown aggregation are skipped and reported in
+// the returned error.
+func resourceMetrics(rm *metricdata.ResourceMetrics) (*metricspb.ResourceMetrics, error) {
+	var errs []error
+	out := &metricspb.ResourceMetrics{Resource: otlpResource(rm.Resource)}
+	if rm.Resource != nil {
+		out.SchemaUrl = rm.Resource.SchemaURL()
+	}
+	for _, sm := range rm.ScopeMetrics {
+		scope := &metricspb.ScopeMetrics{Scope: otlpScope(sm.Scope), SchemaUrl: sm.Scope.SchemaURL}
+		for _, m := range sm.Metrics {
+			metric := &metricspb.Metric{Name: m.Name, Description: m.Description, Unit: m.Unit}
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				metric.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: numberPoints(data.DataPoints)}}
+			case metricdata.Gauge[float64]:
+				metric.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: numberPoints(data.DataPoints)}}
+			case metricdata.Sum[int64]:
+				metric.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+					AggregationTemporality: temporality(data.Temporality),
+					IsMonotonic:            data.IsMonotonic,
+					DataPoints:             numberPoints(data.DataPoints),
+				}}
+			case metricdata.Sum[float64]:
+				metric.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+					AggregationTemporality: temporality(data.Temporality),
+					IsMonotonic:            data.IsMonotonic,
+					DataPoints:             numberPoints(data.DataPoints),
+				}}
+			case metricdata.Histogram[int64]:
+				metric.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+					AggregationTemporality: temporality(data.Temporality),
+					DataPoints:             histogramPoints(data.DataPoints),
+				}}
+			case metricdata.Histogram[float64]:
+				metric.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+					AggregationTemporality: temporality(data.Temporality),
+					DataPoints:             histogramPoints(data.DataPoints),
+				}}
+			case metricdata.ExponentialHistogram[int64]:
+				metric.Data = &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: &metricspb.ExponentialHistogram{
+					AggregationTemporality: temporality(data.Temporality),
+					DataPoints:             exponentialPoints(data.DataPoints),
+				}}
+			case metricdata.ExponentialHistogram[float64]:
+				metric.Data = &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: &metricspb.ExponentialHistogram{
+					AggregationTemporality: temporality(data.Temporality),
+					DataPoints:             exponentialPoints(data.DataPoints),
+				}}
+			case metricdata.Summary:
+				metric.Data = &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+					DataPoints: summaryPoints(data.DataPoints),
+				}}
+			default:
+				errs = append(errs, fmt.Errorf("metric %s has an unsupported aggregation %T", m.Name, m.Data))
+				continue
+			}
+			scope.Metrics = append(scope.Metrics, metric)
+		}
+		out.ScopeMetrics = append(out.ScopeMetrics, scope)
+	}
+	return out, errors.Join(errs...)
+}
+
+// resourceLogs converts log records into their OTLP message, grouping them by
+// instrumentation scope. The records of a logger provider share its resource.
+func resourceLogs(records []sdklog.Record) *logspb.ResourceLogs {
+	res := records[0].Resource()
+	out := &logspb.ResourceLogs{Resource: otlpResource(&res), SchemaUrl: res.SchemaURL()}
+	scopes := make(map[string]*logspb.ScopeLogs)
+	for _, r := range records {
+		s := r.InstrumentationScope()
+		key := s.Name + "\x00" + s.Version + "\x00" + s.SchemaURL
+		scope, ok := scopes[key]
+		if !ok {
+			scope = &logspb.ScopeLogs{Scope: otlpScope(s), SchemaUrl: s.SchemaURL}
+			scopes[key] = scope
+			out.ScopeLogs = append(out.ScopeLogs, scope)
+		}
+		scope.LogRecords = append(scope.LogRecords, logRecord(r))
+	}
+	return out
+}
+
+func logRecord(r sdklog.Record) *logspb.LogRecord {
+	out := &logspb.LogRecord{
+		TimeUnixNano:           unixNano(r.Timestamp()),
+		ObservedTimeUnixNano:   unixNano(r.ObservedTimestamp()),
+		SeverityNumber:         logspb.SeverityNumber(r.Severity()),
+		SeverityText:           r.SeverityText(),
+		Body:                   logValue(r.Body()),
+		DroppedAttributesCount: uint32(r.DroppedAttributes()),
+		Flags:                  uint32(r.TraceFlags()),
+	}
+	r.WalkAttributes(func(kv log.KeyValue) bool {
+		out.Attributes = append(out.Attributes, &commonpb.KeyValue{Key: kv.Key, Value: logValue(kv.Value)})
+		return true
+	})
+	if id := r.TraceID(); id.IsValid() {
+		out.TraceId = id[:]
+	}
+	if id := r.SpanID(); id.IsValid() {
+		out.SpanId = id[:]
+	}
+	return out
+}
+
+func logValue(v log.Value) *commonpb.AnyValue {
+	switch v.Kind() {
+	case log.KindBool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case log.KindInt64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case log.KindFloat64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case log.KindString:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	case log.KindBytes:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v.AsBytes()}}
+	case log.KindSlice:
+		array := &commonpb.ArrayValue{}
+		for _, item := range v.AsSlice() {
+			array.Values = append(array.Values, logValue(item))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: array}}
+	case log.KindMap:
+		kvs := &commonpb.KeyValueList{}
+		for _, kv := range v.AsMap() {
+			kvs.Values = append(kvs.Values, &commonpb.KeyValue{Key: kv.Key, Value: logValue(kv.Value)})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: kvs}}
+	}
+	return nil
+}
+
+func numberPoints[N int64 | float64](dps []metricdata.DataPoint[N]) []*metricspb.NumberDataPoint {
+	out := make([]*metricspb.NumberDataPoint, 0, len(dps))
+	for _, dp := range dps {
+		p := &metricspb.NumberDataPoint{
+			Attributes:        otlpAttributes(dp.Attributes.ToSlice()),
+			StartTimeUnixNano: unixNano(dp.StartTime),
+			TimeUnixNano:      unixNano(dp.Time),
+		}
+		switch v := any(dp.Value).(type) {
+		case int64:
+			p.Value = &metricspb.NumberDataPoint_AsInt{AsInt: v}
+		case float64:
+			p.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: v}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func histogramPoints[N int64 | float64](dps []metricdata.HistogramDataPoint[N]) []*metricspb.HistogramDataPoint {
+	out := make([]*metricspb.HistogramDataPoint, 0, len(dps))
+	for _, dp := range dps {
+		sum := float64(dp.Sum)
+		out = append(out, &metricspb.HistogramDataPoint{
+			Attributes:        otlpAttributes(dp.Attributes.ToSlice()),
+			StartTimeUnixNano: unixNano(dp.StartTime),
+			TimeUnixNano:      unixNano(dp.Time),
+			Count:             dp.Count,
+			Sum:               &sum,
+			BucketCounts:      dp.BucketCounts,
+			ExplicitBounds:    dp.Bounds,
+			Min:               extremum(dp.Min),
+			Max:               extremum(dp.Max),
+		})
+	}
+	return out
+}
+
+func exponentialPoints[N int64 | float64](dps []metricdata.ExponentialHistogramDataPoint[N]) []*metricspb.ExponentialHistogramDataPoint {
+	out := make([]*metricspb.ExponentialHistogramDataPoint, 0, len(dps))
+	for _, dp := range dps {
+		sum := float64(dp.Sum)
+		out = append(out, &metricspb.ExponentialHistogramDataPoint{
+			Attributes:        otlpAttributes(dp.Attributes.ToSlice()),
+			StartTimeUnixNano: unixNano(dp.StartTime),
+			TimeUnixNano:      unixNano(dp.Time),
+			Count:             dp.Count,
+			Sum:               &sum,
+			Scale:             dp.Scale,
+			ZeroCount:         dp.ZeroCount,
+			ZeroThreshold:     dp.ZeroThreshold,
+			Positive: &metricspb.ExponentialHistogramDataPoint_Buckets{
+				Offset:       dp.PositiveBucket.Offset,
+				BucketCounts: dp.PositiveBucket.Counts,
+			},
+			Negative: &metricspb.ExponentialHistogramDataPoint_Buckets{
+				Offset:       dp.NegativeBucket.Offset,
+				BucketCounts: dp.NegativeBucket.Counts,
+			},
+			Min: extremum(dp.Min),
+			Max: extremum(dp.Max),
+		})
+	}
+	return out
+}
+
+func summaryPoints(dps []metricdata.SummaryDataPoint) []*metricspb.SummaryDataPoint {
+	out := make([]*metricspb.SummaryDataPoint, 0, len(dps))
+	for _, dp := range dps {
+		p := &metricspb.SummaryDataPoint{
+			Attributes:        otlpAttributes(dp.Attributes.ToSlice()),
+			StartTimeUnixNano: unixNano(dp.StartTime),
+			TimeUnixNano:      unixNano(dp.Time),
+			Count:             dp.Count,
+			Sum:               dp.Sum,
+		}
+		for _, q := range dp.QuantileValues {
+			p.QuantileValues = append(p.QuantileValues, &metricspb.SummaryDataPoint_ValueAtQuantile{
+				Quantile: q.Quantile,
+				Value:    q.Value,
+			})
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// extremum returns the value of a histogram minimum or maximum, or nil if it is not set
+func extremum[N int64 | float64](e metricdata.Extrema[N]) *float64 {
+	v, ok := e.Value()
+	if !ok {
+		return nil
+	}
+	f := float64(v)
+	return &f
+}
+
+func temporality(t metricdata.Temporality) metricspb.AggregationTemporality {
+	switch t {
+	case metricdata.DeltaTemporality:
+		return metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	case metricdata.CumulativeTemporality:
+		return metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	}
+	return metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_UNSPECIFIED
+}
+
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func otlpResource(res *resource.Resource) *resourcepb.Resource {
+	if res == nil {
+		return nil
+	}
+	return &resourcepb.Resource{Attributes: otlpAttributes(res.Attributes())}
+}
+
+func otlpScope(s instrumentation.Scope) *commonpb.InstrumentationScope {
+	return &commonpb.InstrumentationScope{
+		Name:       s.Name,
+		Version:    s.Version,
+		Attributes: otlpAttributes(s.Attributes.ToSlice()),
+	}
+}
+
+func otlpAttributes(attrs []attribute.KeyValue) []*commonpb.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		out = append(out, &commonpb.KeyValue{Key: string(kv.Key), Value: otlpValue(kv.Value)})
+	}
+	return out
+}
+
+func otlpValue(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.BOOLSLICE:
+		return arrayValue(v.AsBoolSlice(), attribute.BoolValue)
+	case attribute.INT64SLICE:
+		return arrayValue(v.AsInt64Slice(), attribute.Int64Value)
+	case attribute.FLOAT64SLICE:
+		return arrayValue(v.AsFloat64Slice(), attribute.Float64Value)
+	case attribute.STRINGSLICE:
+		return arrayValue(v.AsStringSlice(), attribute.StringValue)
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+}
+
+func arrayValue[T any](values []T, value func(T) attribute.Value) *commonpb.AnyValue {
+	array := &commonpb.ArrayValue{Values: make([]*commonpb.AnyValue, 0, len(values))}
+	for _, v := range values {
+		array.Values = append(array.Values, otlpValue(value(v)))
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: array}}
+}
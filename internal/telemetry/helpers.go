@@ -30,7 +30,7 @@ func (t *Telemetry) NewCommandMetrics() (*CommandMetrics, error) {
 	executionCounter, err := meter.Int64Counter(
 		"valet.command.executions",
 		metric.WithDescription("Total number of command executions"),
-		metric.WithUnit("1"),
+		metric.WithUnit("{execution}"),
 	)
 	if err != nil {
 		return nil, err
@@ -48,7 +48,7 @@ func (t *Telemetry) NewCommandMetrics() (*CommandMetrics, error) {
 	errorCounter, err := meter.Int64Counter(
 		"valet.command.errors",
 		metric.WithDescription("Total number of command errors"),
-		metric.WithUnit("1"),
+		metric.WithUnit("{error}"),
 	)
 	if err != nil {
 		return nil, err
@@ -174,7 +174,7 @@ func (t *Telemetry) NewFileOperationMetrics() (*FileOperationMetrics, error) {
 	readCounter, err := meter.Int64Counter(
 		"valet.file.reads",
 		metric.WithDescription("Total number of file read operations"),
-		metric.WithUnit("1"),
+		metric.WithUnit("{read}"),
 	)
 	if err != nil {
 		return nil, err
@@ -183,7 +183,7 @@ func (t *Telemetry) NewFileOperationMetrics() (*FileOperationMetrics, error) {
 	writeCounter, err := meter.Int64Counter(
 		"valet.file.writes",
 		metric.WithDescription("Total number of file write operations"),
-		metric.WithUnit("1"),
+		metric.WithUnit("{write}"),
 	)
 	if err != nil {
 		return nil, err
@@ -259,7 +259,7 @@ func (t *Telemetry) NewSchemaGenerationMetrics() (*SchemaGenerationMetrics, erro
 	generationCounter, err := meter.Int64Counter(
 		"valet.schema.generations",
 		metric.WithDescription("Total number of schema generations"),
-		metric.WithUnit("1"),
+		metric.WithUnit("{generation}"),
 	)
 	if err != nil {
 		return nil, err
@@ -268,7 +268,7 @@ func (t *Telemetry) NewSchemaGenerationMetrics() (*SchemaGenerationMetrics, erro
 	fieldCounter, err := meter.Int64Histogram(
 		"valet.schema.fields",
 		metric.WithDescription("Number of fields in generated schemas"),
-		metric.WithUnit("1"),
+		metric.WithUnit("{field}"),
 	)
 	if err != nil {
 		return nil, err
@@ -15,8 +15,9 @@ type Logger struct {
 	*zap.Logger
 }
 
-// NewLogger creates a new zap logger with OpenTelemetry integration
-func NewLogger(debug bool) (*Logger, error) {
+// NewLogger creates a new zap logger with OpenTelemetry integration. Entries are also
+// written to cores, such as the bridge to the OpenTelemetry logs pipeline.
+func NewLogger(debug bool, cores ...zapcore.Core) (*Logger, error) {
 	config := zap.NewProductionConfig()
 
 	// Set log level based on debug flag
@@ -44,7 +45,13 @@ func NewLogger(debug bool) (*Logger, error) {
 	config.EncoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
 
 	// Build the logger
-	logger, err := config.Build()
+	var opts []zap.Option
+	if len(cores) > 0 {
+		opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(append([]zapcore.Core{core}, cores...)...)
+		}))
+	}
+	logger, err := config.Build(opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -75,52 +82,58 @@ func (l *Logger) WithContext(ctx context.Context) *zap.Logger {
 	)
 }
 
+// contextField carries ctx to the OpenTelemetry log bridge, which takes the trace and span
+// of a log record from it. Other encoders skip the field.
+func contextField(ctx context.Context) zap.Field {
+	return zap.Field{Key: "context", Type: zapcore.SkipType, Interface: ctx}
+}
+
 // Debug logs a debug message with OpenTelemetry context
 func (l *Logger) Debug(ctx context.Context, msg string, fields ...zap.Field) {
 	logger := l.WithContext(ctx)
-	logger.Debug(msg, fields...)
+	logger.Debug(msg, append(fields, contextField(ctx))...)
 	l.addSpanEvent(ctx, zap.DebugLevel, msg, fields...)
 }
 
 // Info logs an info message with OpenTelemetry context
 func (l *Logger) Info(ctx context.Context, msg string, fields ...zap.Field) {
 	logger := l.WithContext(ctx)
-	logger.Info(msg, fields...)
+	logger.Info(msg, append(fields, contextField(ctx))...)
 	l.addSpanEvent(ctx, zap.InfoLevel, msg, fields...)
 }
 
 // Warn logs a warning message with OpenTelemetry context
 func (l *Logger) Warn(ctx context.Context, msg string, fields ...zap.Field) {
 	logger := l.WithContext(ctx)
-	logger.Warn(msg, fields...)
+	logger.Warn(msg, append(fields, contextField(ctx))...)
 	l.addSpanEvent(ctx, zap.WarnLevel, msg, fields...)
 }
 
 // Error logs an error message with OpenTelemetry context
 func (l *Logger) Error(ctx context.Context, msg string, fields ...zap.Field) {
 	logger := l.WithContext(ctx)
-	logger.Error(msg, fields...)
+	logger.Error(msg, append(fields, contextField(ctx))...)
 	l.addSpanEvent(ctx, zap.ErrorLevel, msg, fields...)
 }
 
 // DPanic logs a message at DPanicLevel with OpenTelemetry context
 func (l *Logger) DPanic(ctx context.Context, msg string, fields ...zap.Field) {
 	logger := l.WithContext(ctx)
-	logger.DPanic(msg, fields...)
+	logger.DPanic(msg, append(fields, contextField(ctx))...)
 	l.addSpanEvent(ctx, zap.DPanicLevel, msg, fields...)
 }
 
 // Panic logs a message at PanicLevel with OpenTelemetry context
 func (l *Logger) Panic(ctx context.Context, msg string, fields ...zap.Field) {
 	logger := l.WithContext(ctx)
-	logger.Panic(msg, fields...)
+	logger.Panic(msg, append(fields, contextField(ctx))...)
 	l.addSpanEvent(ctx, zap.PanicLevel, msg, fields...)
 }
 
 // Fatal logs a message at FatalLevel with OpenTelemetry context
 func (l *Logger) Fatal(ctx context.Context, msg string, fields ...zap.Field) {
 	logger := l.WithContext(ctx)
-	logger.Fatal(msg, fields...)
+	logger.Fatal(msg, append(fields, contextField(ctx))...)
 	l.addSpanEvent(ctx, zap.FatalLevel, msg, fields...)
 }
 
@@ -0,0 +1,99 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mkm29/valet/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// promMetrics exposes the metrics read by the prometheus exporter in the Prometheus text format
+type promMetrics struct {
+	cfg      config.PrometheusConfig
+	registry *prometheus.Registry
+	server   *http.Server
+}
+
+// newPrometheus creates the metric reader of the prometheus exporter, registering the
+// metrics with a registry of their own rather than the global one
+func newPrometheus(cfg config.PrometheusConfig) (*promMetrics, sdkmetric.Reader, error) {
+	registry := prometheus.NewRegistry()
+	reader, err := otelprom.New(otelprom.WithRegisterer(registry))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create prometheus exporter: %w", err)
+	}
+	return &promMetrics{cfg: cfg, registry: registry}, reader, nil
+}
+
+// serve starts serving /metrics on the listen address and returns the address listened on
+func (p *promMetrics) serve() (string, error) {
+	if p.server != nil {
+		return "", fmt.Errorf("metrics are already served")
+	}
+	ln, err := net.Listen("tcp", p.cfg.Listen)
+	if err != nil {
+		return "", fmt.Errorf("failed to serve metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{}))
+	p.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = p.server.Serve(ln)
+	}()
+	return ln.Addr().String(), nil
+}
+
+// shutdown stops serving the metrics or, when they were never served, writes them to the
+// textfile. The file is replaced atomically, so a collector never reads a partial file.
+func (p *promMetrics) shutdown(ctx context.Context) error {
+	if p.server != nil {
+		return p.server.Shutdown(ctx)
+	}
+	if err := writeTextfile(p.cfg.Textfile, p.registry); err != nil {
+		return fmt.Errorf("failed to write metrics textfile: %w", err)
+	}
+	return nil
+}
+
+// writeTextfile writes the gathered metrics to path in the classic text format, with the
+// dots of OpenTelemetry names replaced by underscores as textfile collectors expect. The
+// metrics are written to a temporary file first, without the .prom extension collectors
+// read, and renamed over path.
+func writeTextfile(path string, g prometheus.Gatherer) error {
+	families, err := g.Gather()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := expfmt.NewEncoder(tmp, expfmt.NewFormat(expfmt.TypeTextPlain).WithEscapingScheme(model.UnderscoreEscaping))
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -5,23 +5,23 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"time"
+	"syscall"
 
 	"github.com/mkm29/valet/internal/config"
+	"go.opentelemetry.io/contrib/bridges/otelzap"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
-	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
-	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/log/global"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
 )
 
 // Telemetry holds the telemetry providers and instruments
@@ -29,6 +29,8 @@ type Telemetry struct {
 	config         *config.TelemetryConfig
 	tracerProvider *sdktrace.TracerProvider
 	meterProvider  *sdkmetric.MeterProvider
+	loggerProvider *sdklog.LoggerProvider
+	prometheus     *promMetrics
 	tracer         oteltrace.Tracer
 	meter          metric.Meter
 	logger         *Logger
@@ -51,24 +53,47 @@ func Initialize(ctx context.Context, cfg *config.TelemetryConfig) (*Telemetry, e
 	}
 
 	// Initialize tracer provider
-	tracerProvider, err := initTracerProvider(ctx, cfg, res)
+	tracerProvider, err := initTracerProvider(ctx, cfg.TracesExporter(), cfg.TraceSampleRate(), res)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize tracer provider: %w", err)
 	}
 
 	// Initialize meter provider
-	meterProvider, err := initMeterProvider(ctx, cfg, res)
+	meterProvider, prom, err := initMeterProvider(ctx, cfg.MetricsExporter(), res)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize meter provider: %w", err)
 	}
 
+	// Initialize logger provider
+	logsExporter := cfg.LogsExporter()
+	loggerProvider, err := initLoggerProvider(ctx, logsExporter, res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize logger provider: %w", err)
+	}
+
 	// Set global providers
 	otel.SetTracerProvider(tracerProvider)
 	otel.SetMeterProvider(meterProvider)
+	global.SetLoggerProvider(loggerProvider)
 	otel.SetTextMapPropagator(propagation.TraceContext{})
 
-	// Create structured logger
-	logger, err := NewLogger(false) // Debug will be controlled by the caller
+	// Create structured logger, bridged to the logs pipeline unless logs are disabled
+	var cores []zapcore.Core
+	if logsExporter.Type != "none" {
+		level, err := zapcore.ParseLevel(cfg.LogLevel())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse log level: %w", err)
+		}
+		core, err := zapcore.NewIncreaseLevelCore(
+			otelzap.NewCore(cfg.ServiceName, otelzap.WithLoggerProvider(loggerProvider)),
+			level,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create log bridge: %w", err)
+		}
+		cores = append(cores, core)
+	}
+	logger, err := NewLogger(false, cores...) // Debug will be controlled by the caller
 	if err != nil {
 		return nil, fmt.Errorf("failed to create logger: %w", err)
 	}
@@ -79,6 +104,8 @@ func Initialize(ctx context.Context, cfg *config.TelemetryConfig) (*Telemetry, e
 		config:         cfg,
 		tracerProvider: tracerProvider,
 		meterProvider:  meterProvider,
+		loggerProvider: loggerProvider,
+		prometheus:     prom,
 		tracer:         tracerProvider.Tracer(cfg.ServiceName),
 		meter:          meterProvider.Meter(cfg.ServiceName),
 		logger:         logger,
@@ -101,19 +128,32 @@ func (t *Telemetry) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	// Serve or write the Prometheus metrics while the meter provider can still be read
+	if t.prometheus != nil {
+		if err := t.prometheus.shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	if t.meterProvider != nil {
 		if err := t.meterProvider.Shutdown(ctx); err != nil {
 			errs = append(errs, fmt.Errorf("failed to shutdown meter provider: %w", err))
 		}
 	}
 
-	// Sync logger
+	// Sync logger before shutting down the logs pipeline it writes to
 	if t.logger != nil {
-		if err := t.logger.Sync(); err != nil {
+		if err := syncErrors(t.logger.Sync()); err != nil {
 			errs = append(errs, fmt.Errorf("failed to sync logger: %w", err))
 		}
 	}
 
+	if t.loggerProvider != nil {
+		if err := t.loggerProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown logger provider: %w", err))
+		}
+	}
+
 	if len(errs) > 0 {
 		return errors.Join(errs...)
 	}
@@ -142,6 +182,16 @@ func (t *Telemetry) IsEnabled() bool {
 	return t != nil && t.config != nil && t.config.Enabled
 }
 
+// ServeMetrics serves /metrics when metrics are exported to Prometheus, for long-running
+// commands; otherwise the metrics are written to a textfile on shutdown. It returns the
+// address served, or "" when metrics are not exported to Prometheus.
+func (t *Telemetry) ServeMetrics() (string, error) {
+	if t == nil || t.prometheus == nil {
+		return "", nil
+	}
+	return t.prometheus.serve()
+}
+
 // Logger returns the structured logger
 func (t *Telemetry) Logger() *Logger {
 	if t == nil || t.logger == nil {
@@ -168,85 +218,126 @@ func newResource(cfg *config.TelemetryConfig) (*resource.Resource, error) {
 }
 
 // initTracerProvider initializes the tracer provider
-func initTracerProvider(ctx context.Context, cfg *config.TelemetryConfig, res *resource.Resource) (*sdktrace.TracerProvider, error) {
-	var exporter sdktrace.SpanExporter
-	var err error
-
-	switch cfg.ExporterType {
-	case "otlp":
-		opts := []otlptracegrpc.Option{
-			otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint),
-		}
-		if cfg.Insecure {
-			opts = append(opts, otlptracegrpc.WithInsecure())
-		}
-		if len(cfg.Headers) > 0 {
-			opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
-		}
-		exporter, err = otlptracegrpc.New(ctx, opts...)
-	case "stdout":
-		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
-	default:
+func initTracerProvider(ctx context.Context, cfg config.Exporter, sampleRate float64, res *resource.Resource) (*sdktrace.TracerProvider, error) {
+	exporter, err := newSpanExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	if exporter == nil {
 		// No exporter (noop)
 		return sdktrace.NewTracerProvider(
 			sdktrace.WithResource(res),
-			sdktrace.WithSampler(sdktrace.TraceIDRatioBased(cfg.SampleRate)),
+			sdktrace.WithSampler(sampler(sampleRate)),
 		), nil
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
-	}
-
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(exporter,
-			sdktrace.WithBatchTimeout(5*time.Second),
+			sdktrace.WithBatchTimeout(cfg.Interval),
 		),
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.TraceIDRatioBased(cfg.SampleRate)),
+		sdktrace.WithSampler(sampler(sampleRate)),
 	)
 
 	return tp, nil
 }
 
-// initMeterProvider initializes the meter provider
-func initMeterProvider(ctx context.Context, cfg *config.TelemetryConfig, res *resource.Resource) (*sdkmetric.MeterProvider, error) {
-	var exporter sdkmetric.Exporter
-	var err error
+// sampler samples root spans at sampleRate and follows the sampling decision of the parent
+// otherwise, so that a run joining a pipeline trace is recorded when the pipeline is
+func sampler(sampleRate float64) sdktrace.Sampler {
+	return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRate))
+}
 
-	switch cfg.ExporterType {
-	case "otlp":
-		opts := []otlpmetricgrpc.Option{
-			otlpmetricgrpc.WithEndpoint(cfg.OTLPEndpoint),
-		}
-		if cfg.Insecure {
-			opts = append(opts, otlpmetricgrpc.WithInsecure())
-		}
-		if len(cfg.Headers) > 0 {
-			opts = append(opts, otlpmetricgrpc.WithHeaders(cfg.Headers))
+// initMeterProvider initializes the meter provider, and the Prometheus metrics when they are
+// exported to Prometheus
+func initMeterProvider(ctx context.Context, cfg config.Exporter, res *resource.Resource) (*sdkmetric.MeterProvider, *promMetrics, error) {
+	if cfg.Type == "prometheus" {
+		// Prometheus pulls the metrics rather than being pushed them
+		prom, reader, err := newPrometheus(cfg.Prometheus)
+		if err != nil {
+			return nil, nil, err
 		}
-		exporter, err = otlpmetricgrpc.New(ctx, opts...)
-	case "stdout":
-		exporter, err = stdoutmetric.New()
-	default:
-		// No exporter (noop)
 		return sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(reader),
 			sdkmetric.WithResource(res),
-		), nil
+		), prom, nil
 	}
 
+	exporter, err := newMetricExporter(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+		return nil, nil, fmt.Errorf("failed to create metric exporter: %w", err)
+	}
+	if exporter == nil {
+		// No exporter (noop)
+		return sdkmetric.NewMeterProvider(
+			sdkmetric.WithResource(res),
+		), nil, nil
 	}
 
 	mp := sdkmetric.NewMeterProvider(
 		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter,
-			sdkmetric.WithInterval(30*time.Second),
+			sdkmetric.WithInterval(cfg.Interval),
 		)),
 		sdkmetric.WithResource(res),
 	)
 
-	return mp, nil
+	return mp, nil, nil
+}
+
+// syncErrors drops the errors of syncing an output that cannot be synced, such as stderr
+// attached to a terminal or a pipe, from the error returned by a logger's Sync
+func syncErrors(err error) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range joined.Unwrap() {
+			if e = syncErrors(e); e != nil {
+				errs = append(errs, e)
+			}
+		}
+		return errors.Join(errs...)
+	}
+	if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY) {
+		return nil
+	}
+	return err
+}
+
+// initLoggerProvider initializes the logger provider
+func initLoggerProvider(ctx context.Context, cfg config.Exporter, res *resource.Resource) (*sdklog.LoggerProvider, error) {
+	exporter, err := newLogExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log exporter: %w", err)
+	}
+	if exporter == nil {
+		// No exporter (noop)
+		return sdklog.NewLoggerProvider(
+			sdklog.WithResource(res),
+		), nil
+	}
+
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter,
+			sdklog.WithExportInterval(cfg.Interval),
+		)),
+		sdklog.WithResource(res),
+	)
+
+	return lp, nil
+}
+
+// ContextFromEnv returns ctx with the remote parent span given by the TRACEPARENT and
+// TRACESTATE environment variables, as set by otel-cli and CI tracing plugins, so that the
+// spans of a run join the trace of the pipeline that started it. Without a valid
+// TRACEPARENT, ctx is returned unchanged and spans start a new trace.
+func ContextFromEnv(ctx context.Context) context.Context {
+	carrier := propagation.MapCarrier{}
+	if v, ok := os.LookupEnv("TRACEPARENT"); ok {
+		carrier.Set("traceparent", v)
+	}
+	if v, ok := os.LookupEnv("TRACESTATE"); ok {
+		carrier.Set("tracestate", v)
+	}
+	return propagation.TraceContext{}.Extract(ctx, carrier)
 }
 
 // StartSpan starts a new span
@@ -2,13 +2,18 @@ package telemetry
 
 import (
 	"context"
+	"os"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/mkm29/valet/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func TestInitializeAndShutdown(t *testing.T) {
@@ -35,6 +40,23 @@ func TestInitializeAndShutdown(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "separate tracing and metrics exporters",
+			cfg: &config.TelemetryConfig{
+				Enabled:        true,
+				ExporterType:   "none",
+				ServiceName:    "test-service",
+				ServiceVersion: "1.0.0",
+				SampleRate:     1.0,
+				Tracing: config.TracingConfig{
+					SignalConfig: config.SignalConfig{Exporter: "stdout", ExportInterval: 1},
+				},
+				Metrics: config.MetricsConfig{
+					SignalConfig: config.SignalConfig{Exporter: "stdout", ExportInterval: 60},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "none exporter",
 			cfg: &config.TelemetryConfig{
@@ -152,3 +174,65 @@ func TestRecordError(t *testing.T) {
 		RecordError(ctx, assert.AnError)
 	})
 }
+
+func TestContextFromEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		wantValid   bool
+	}{
+		{"sampled parent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"no parent", "", false},
+		{"invalid parent", "00-not-a-trace-01", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.traceparent != "" {
+				t.Setenv("TRACEPARENT", tt.traceparent)
+			}
+			t.Setenv("TRACESTATE", "ci=job-42")
+
+			sc := oteltrace.SpanContextFromContext(ContextFromEnv(context.Background()))
+			assert.Equal(t, tt.wantValid, sc.IsValid())
+			if tt.wantValid {
+				assert.True(t, sc.IsRemote())
+				assert.True(t, sc.IsSampled())
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+				assert.Equal(t, "00f067aa0ba902b7", sc.SpanID().String())
+				assert.Equal(t, "ci=job-42", sc.TraceState().String())
+			}
+		})
+	}
+}
+
+// syncWriter is a log output whose Sync fails with err
+type syncWriter struct {
+	err error
+}
+
+func (w syncWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w syncWriter) Sync() error                 { return w.err }
+
+func TestTelemetryShutdownSyncErrors(t *testing.T) {
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	newTelemetry := func(errs ...error) *Telemetry {
+		var cores []zapcore.Core
+		for _, err := range errs {
+			cores = append(cores, zapcore.NewCore(encoder, syncWriter{err: &os.PathError{Op: "sync", Path: "/dev/stderr", Err: err}}, zapcore.InfoLevel))
+		}
+		return &Telemetry{
+			config: &config.TelemetryConfig{Enabled: true},
+			logger: &Logger{Logger: zap.New(zapcore.NewTee(cores...))},
+		}
+	}
+
+	// Terminals and pipes cannot be synced
+	assert.NoError(t, newTelemetry(syscall.EINVAL, syscall.ENOTTY).Shutdown(context.Background()))
+
+	// Other sync failures are still reported
+	err := newTelemetry(syscall.EINVAL, syscall.EIO).Shutdown(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to sync logger")
+	assert.ErrorIs(t, err, syscall.EIO)
+	assert.NotErrorIs(t, err, syscall.EINVAL)
+}
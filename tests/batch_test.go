@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+// writeBatchChart writes a chart with the given values.yaml below root
+func (ts *ValetTestSuite) writeBatchChart(root, dir, values string) string {
+	chartDir := filepath.Join(root, dir)
+	ts.Require().NoError(os.MkdirAll(chartDir, 0755))
+	ts.Require().NoError(os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: demo\nversion: 0.1.0\n"), 0644))
+	ts.Require().NoError(os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte(values), 0644))
+	return chartDir
+}
+
+// TestGenerate_Recursive ensures every chart below the root gets a schema and failures are summarized
+func (ts *ValetTestSuite) TestGenerate_Recursive() {
+	root := ts.T().TempDir()
+	api := ts.writeBatchChart(root, "services/api", "replicaCount: 1\n")
+	web := ts.writeBatchChart(root, "web", "image:\n  tag: latest\n")
+	broken := ts.writeBatchChart(root, "broken", "a: [1\n")
+	// Vendored subcharts are not generated
+	vendored := ts.writeBatchChart(root, "web/charts/redis", "port: 6379\n")
+
+	var out bytes.Buffer
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"generate", "--recursive", "--jobs", "2", root})
+	err := rootCmd.Execute()
+	ts.Require().Error(err, "expected the broken chart to fail the run")
+	ts.Contains(err.Error(), "1 of 3 charts failed")
+
+	ts.FileExists(filepath.Join(api, "values.schema.json"))
+	ts.FileExists(filepath.Join(web, "values.schema.json"))
+	ts.NoFileExists(filepath.Join(broken, "values.schema.json"))
+	ts.NoFileExists(filepath.Join(vendored, "values.schema.json"))
+
+	summary := out.String()
+	ts.Contains(summary, "CHART")
+	ts.Regexp(`services/api\s+ok\s+1\s`, summary)
+	ts.Regexp(`broken\s+failed\s`, summary)
+	ts.Contains(summary, "3 charts, 1 failed, 0 up to date")
+}
+
+// TestGenerateAll_NoCharts ensures a root without charts is an error
+func (ts *ValetTestSuite) TestGenerateAll_NoCharts() {
+	_, err := cmd.GenerateAll(ts.T().TempDir(), "", 4)
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "no charts")
+}
+
+// TestGenerate_RecursiveChartConfig ensures each chart reads the config discovered in its own
+// directory, and a chart missing the overrides file fails like a single chart does
+func (ts *ValetTestSuite) TestGenerate_RecursiveChartConfig() {
+	root := ts.T().TempDir()
+	api := ts.writeBatchChart(root, "api", "replicaCount: 1\n")
+	web := ts.writeBatchChart(root, "web", "replicaCount: 1\n")
+	ts.Require().NoError(os.WriteFile(filepath.Join(api, ".valet.yaml"), []byte("output: api.schema.json\n"), 0644))
+	ts.Require().NoError(os.WriteFile(filepath.Join(api, "overrides.yaml"), []byte("replicaCount: 2\n"), 0644))
+
+	var out bytes.Buffer
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"generate", "--recursive", "--overrides", "overrides.yaml", root})
+	err := rootCmd.Execute()
+	ts.Require().Error(err, "expected the chart without overrides to fail the run")
+	ts.Contains(err.Error(), "1 of 2 charts failed")
+
+	ts.FileExists(filepath.Join(api, "api.schema.json"))
+	ts.NoFileExists(filepath.Join(api, "values.schema.json"))
+	ts.NoFileExists(filepath.Join(web, "values.schema.json"))
+	ts.Regexp(`web\s+failed\s.*overrides file overrides.yaml not found in `, out.String())
+}
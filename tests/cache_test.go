@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+// TestGenerate_Cache ensures --cache skips charts whose inputs and output are unchanged
+func (ts *ValetTestSuite) TestGenerate_Cache() {
+	ts.T().Setenv("XDG_CACHE_HOME", ts.T().TempDir())
+	tmp := ts.T().TempDir()
+	valuesPath := filepath.Join(tmp, "values.yaml")
+	outPath := filepath.Join(tmp, "values.schema.json")
+	ts.Require().NoError(os.WriteFile(valuesPath, []byte("replicaCount: 1\n"), 0644))
+
+	generate := func() {
+		rootCmd := cmd.NewRootCmd()
+		rootCmd.SetArgs([]string{"generate", "--cache", tmp})
+		ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+	}
+	// modified reports whether a generation rewrote the schema, by backdating it first
+	modified := func() bool {
+		old := time.Now().Add(-time.Hour).Truncate(time.Second)
+		ts.Require().NoError(os.Chtimes(outPath, old, old))
+		generate()
+		info, err := os.Stat(outPath)
+		ts.Require().NoError(err)
+		return !info.ModTime().Equal(old)
+	}
+
+	generate()
+	ts.FileExists(outPath)
+	ts.False(modified(), "expected unchanged inputs to be skipped")
+
+	ts.Require().NoError(os.WriteFile(valuesPath, []byte("replicaCount: 1\nimage: nginx\n"), 0644))
+	ts.True(modified(), "expected changed values to regenerate the schema")
+	ts.False(modified(), "expected the new inputs to be cached")
+
+	// An output edited by hand is regenerated
+	ts.Require().NoError(os.WriteFile(outPath, []byte("{}"), 0644))
+	ts.True(modified(), "expected an edited output to be regenerated")
+
+	// Without --cache the schema is always written
+	rootCmd := cmd.NewRootCmd()
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	ts.Require().NoError(os.Chtimes(outPath, old, old))
+	rootCmd.SetArgs([]string{"generate", tmp})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+	info, err := os.Stat(outPath)
+	ts.Require().NoError(err)
+	ts.NotEqual(old, info.ModTime())
+}
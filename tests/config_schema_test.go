@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mkm29/valet/cmd"
+	"github.com/mkm29/valet/internal/config"
+)
+
+// yamlKeys returns the sorted YAML keys of a config struct, including those of inlined structs
+func yamlKeys(v any) []string {
+	var keys []string
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")
+		if len(tag) > 1 && tag[1] == "inline" {
+			keys = append(keys, yamlKeys(reflect.New(t.Field(i).Type).Elem().Interface())...)
+			continue
+		}
+		if tag[0] != "" && tag[0] != "-" {
+			keys = append(keys, tag[0])
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// schemaKeys returns the sorted property names of an object schema
+func schemaKeys(schema map[string]any) []string {
+	var keys []string
+	for k := range schema["properties"].(map[string]any) {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// schemaEnum returns the enum of a property schema as strings
+func schemaEnum(schema map[string]any, key string) []string {
+	var values []string
+	for _, v := range schema["properties"].(map[string]any)[key].(map[string]any)["enum"].([]any) {
+		values = append(values, v.(string))
+	}
+	return values
+}
+
+// TestConfigSchema_InSync ensures the published config schema matches the config structs
+func (ts *ValetTestSuite) TestConfigSchema_InSync() {
+	data, err := os.ReadFile(filepath.Join("..", "schemas", "valet-config.schema.json"))
+	ts.Require().NoError(err, "failed to read config schema")
+	var schema map[string]any
+	ts.Require().NoError(json.Unmarshal(data, &schema), "config schema is not valid JSON")
+	defs := schema["definitions"].(map[string]any)
+
+	for name, c := range map[string]struct {
+		schema map[string]any
+		value  any
+	}{
+		"config":     {schema, config.Config{}},
+		"chart":      {defs["chart"].(map[string]any), config.ChartConfig{}},
+		"rule":       {defs["rule"].(map[string]any), config.Rule{}},
+		"telemetry":  {defs["telemetry"].(map[string]any), config.TelemetryConfig{}},
+		"tracing":    {defs["tracing"].(map[string]any), config.TracingConfig{}},
+		"metrics":    {defs["metrics"].(map[string]any), config.MetricsConfig{}},
+		"logs":       {defs["logs"].(map[string]any), config.LogsConfig{}},
+		"tls":        {defs["tls"].(map[string]any), config.TLSConfig{}},
+		"prometheus": {defs["prometheus"].(map[string]any), config.PrometheusConfig{}},
+	} {
+		ts.Equal(yamlKeys(c.value), schemaKeys(c.schema), "properties of %s", name)
+		ts.Equal(false, c.schema["additionalProperties"], "additionalProperties of %s", name)
+	}
+
+	ts.Equal(config.Formats, schemaEnum(schema, "format"))
+	ts.Equal(config.StrictModes, schemaEnum(schema, "strict"))
+	ts.Equal(config.Drafts, schemaEnum(map[string]any{"properties": defs}, "draft"))
+	ts.Equal(config.RuleTypes, schemaEnum(defs["rule"].(map[string]any), "type"))
+	ts.Equal(config.ExporterTypes, schemaEnum(defs["telemetry"].(map[string]any), "exporterType"))
+	ts.Equal(config.ExporterTypes, schemaEnum(defs["tracing"].(map[string]any), "exporter"))
+	ts.Equal(config.MetricExporterTypes, schemaEnum(defs["metrics"].(map[string]any), "exporter"))
+	ts.Equal(config.ExporterTypes, schemaEnum(defs["logs"].(map[string]any), "exporter"))
+	ts.Equal(config.LogLevels, schemaEnum(defs["logs"].(map[string]any), "level"))
+	for _, def := range []string{"telemetry", "tracing", "metrics", "logs"} {
+		ts.Equal(config.Compressions, schemaEnum(defs[def].(map[string]any), "compression"), "compression of %s", def)
+	}
+}
+
+// TestConfigExample_Valid ensures the example config file passes validation
+func (ts *ValetTestSuite) TestConfigExample_Valid() {
+	c, err := config.LoadConfig(filepath.Join("..", "examples", "valet-config.yaml"))
+	ts.Require().NoError(err, "example config does not load")
+	ts.NoError(c.Validate(), "example config is invalid")
+}
+
+// TestConfig_Validate ensures every invalid setting is reported
+func (ts *ValetTestSuite) TestConfig_Validate() {
+	c := &config.Config{
+		Format: "xml",
+		Strict: "pedantic",
+		Rules:  []config.Rule{{Path: "a", Type: "map"}, {Type: "string"}},
+		Charts: []config.ChartConfig{{Context: "charts/app", Draft: "draft-03"}, {}},
+		Telemetry: &config.TelemetryConfig{
+			ExporterType: "zipkin",
+			SampleRate:   1,
+		},
+	}
+	err := c.Validate()
+	ts.Require().Error(err)
+	for _, msg := range []string{
+		`unsupported output format "xml"`,
+		`unsupported strict mode "pedantic"`,
+		`rule a: unsupported type "map"`,
+		"rule 2 has no path",
+		`chart charts/app: unsupported schema draft "draft-03"`,
+		"chart 2 has no context",
+		"telemetry: invalid exporter type: zipkin",
+	} {
+		ts.Contains(err.Error(), msg)
+	}
+
+	ts.NoError((&config.Config{Telemetry: config.NewTelemetryConfig()}).Validate())
+}
+
+// TestConfigValidateCmd ensures config validate reports every file and fails on invalid ones
+func (ts *ValetTestSuite) TestConfigValidateCmd() {
+	dir := ts.T().TempDir()
+	good := filepath.Join(dir, "good.yaml")
+	bad := filepath.Join(dir, "bad.yaml")
+	ts.Require().NoError(os.WriteFile(good, []byte("output: values.schema.yaml\n"), 0644))
+	ts.Require().NoError(os.WriteFile(bad, []byte("debug: true\ntelemetry:\n  metrics:\n    interval: 30\n"), 0644))
+
+	var out bytes.Buffer
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"config", "validate", good, bad})
+	err := rootCmd.Execute()
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "1 of 2 config file(s) invalid")
+	ts.Contains(out.String(), good+": ok")
+	ts.Contains(out.String(), bad+":\n")
+	ts.Contains(out.String(), "line 4: field interval not found")
+
+	// The discovered files are checked when no file is given
+	ts.Require().NoError(os.WriteFile(filepath.Join(dir, ".valet.yaml"), []byte("strict: warn\n"), 0644))
+	wd, err := os.Getwd()
+	ts.Require().NoError(err)
+	ts.Require().NoError(os.Chdir(dir))
+	defer os.Chdir(wd)
+	out.Reset()
+	rootCmd = cmd.NewRootCmd()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"config", "validate"})
+	ts.Require().NoError(rootCmd.Execute())
+	ts.Contains(out.String(), ".valet.yaml: ok")
+}
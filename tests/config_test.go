@@ -121,13 +121,12 @@ func (ts *ValetTestSuite) TestLoadConfig_FilePermissionError() {
 	os.Chmod(tmpFile, 0644)
 }
 
-// TestLoadConfig_ComplexYAML tests loading configs with complex YAML structures
+// TestLoadConfig_ComplexYAML ensures unknown keys are rejected with their line numbers
 func (ts *ValetTestSuite) TestLoadConfig_ComplexYAML() {
-	// Create config with complex YAML (we'll ignore most of these fields)
 	content := `
 debug: true
 context: /complex/context
-# The following fields are ignored but should parse correctly
+# The following fields are unknown and must be reported
 extra:
   nested:
     value: something
@@ -137,16 +136,19 @@ extra:
 mappings:
   key1: value1
   key2: value2
+telemetry:
+  tracing:
+    samplingRate: 1.0
 `
 	tmpFile := filepath.Join(ts.T().TempDir(), "config-complex.yaml")
 	err := os.WriteFile(tmpFile, []byte(content), 0644)
 	ts.Require().NoError(err, "failed to write config")
 
 	// Load config
-	cfg, err := config.LoadConfig(tmpFile)
-	ts.NoError(err, "LoadConfig failed")
-
-	// Verify critical values
-	ts.True(cfg.Debug, "Debug should be true")
-	ts.Equal("/complex/context", cfg.Context, "Context incorrect")
+	_, err = config.LoadConfig(tmpFile)
+	ts.Require().Error(err, "expected unknown keys to be rejected")
+	ts.Contains(err.Error(), "failed to parse config "+tmpFile)
+	ts.Contains(err.Error(), "line 5: field extra not found")
+	ts.Contains(err.Error(), "line 11: field mappings not found")
+	ts.Contains(err.Error(), "line 16: field samplingRate not found")
 }
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/mkm29/valet/cmd"
+	"github.com/mkm29/valet/internal/config"
+)
+
+// writeRepo creates a repository with a chart at charts/app and returns both directories
+func (ts *ValetTestSuite) writeRepo() (string, string) {
+	repo := ts.T().TempDir()
+	chart := filepath.Join(repo, "charts", "app")
+	ts.Require().NoError(os.MkdirAll(filepath.Join(repo, ".git"), 0755))
+	ts.Require().NoError(os.MkdirAll(chart, 0755))
+	ts.Require().NoError(os.WriteFile(filepath.Join(chart, "values.yaml"), []byte("a: 1\n"), 0644))
+	return repo, chart
+}
+
+// TestDiscoverFiles ensures config files are found from the user level down to the context directory
+func (ts *ValetTestSuite) TestDiscoverFiles() {
+	repo, chart := ts.writeRepo()
+	home := os.Getenv("HOME")
+	xdg := filepath.Join(home, ".config", "valet", "config.yaml")
+	ts.Require().NoError(os.MkdirAll(filepath.Dir(xdg), 0755))
+	for _, f := range []string{
+		filepath.Join(home, ".valet.yaml"),
+		xdg,
+		filepath.Join(repo, ".valet.yaml"),
+		filepath.Join(chart, ".valet.yaml"),
+	} {
+		ts.Require().NoError(os.WriteFile(f, []byte("debug: true\n"), 0644))
+	}
+	// Files above the repository root are not read
+	ts.Require().NoError(os.WriteFile(filepath.Join(filepath.Dir(repo), ".valet.yaml"), []byte("debug: true\n"), 0644))
+	defer os.Remove(filepath.Join(filepath.Dir(repo), ".valet.yaml"))
+
+	files := config.DiscoverFiles(chart)
+	ts.Equal([]string{
+		filepath.Join(home, ".valet.yaml"),
+		xdg,
+		filepath.Join(repo, ".valet.yaml"),
+		filepath.Join(chart, ".valet.yaml"),
+	}, files)
+}
+
+// TestLoadFiles_Merge ensures later files override earlier ones key by key, with origins
+func (ts *ValetTestSuite) TestLoadFiles_Merge() {
+	tmp := ts.T().TempDir()
+	user := filepath.Join(tmp, "user.yaml")
+	project := filepath.Join(tmp, "project.yaml")
+	ts.Require().NoError(os.WriteFile(user, []byte("debug: true\noutput: user.json\ntelemetry:\n  enabled: true\n  sampleRate: 0.5\n"), 0644))
+	ts.Require().NoError(os.WriteFile(project, []byte("output: project.json\ntelemetry:\n  sampleRate: 0.25\n"), 0644))
+
+	cfg, err := config.LoadFiles(user, project, filepath.Join(tmp, "missing.yaml"))
+	ts.Require().NoError(err)
+	ts.True(cfg.Debug)
+	ts.Equal("project.json", cfg.Output)
+	ts.True(cfg.Telemetry.Enabled)
+	ts.Equal(0.25, cfg.Telemetry.SampleRate)
+	// Defaults survive the merge
+	ts.Equal("valet", cfg.Telemetry.ServiceName)
+
+	ts.Equal("file "+user, cfg.Origin("debug"))
+	ts.Equal("file "+project, cfg.Origin("output"))
+	ts.Equal("file "+user, cfg.Origin("telemetry.enabled"))
+	ts.Equal("file "+project, cfg.Origin("telemetry.sampleRate"))
+	ts.Equal(config.OriginDefault, cfg.Origin("format"))
+}
+
+// TestGenerate_DiscoveredConfig ensures the .valet.yaml files of the chart and repository are applied
+func (ts *ValetTestSuite) TestGenerate_DiscoveredConfig() {
+	repo, chart := ts.writeRepo()
+	ts.Require().NoError(os.WriteFile(filepath.Join(repo, ".valet.yaml"), []byte("output: repo.json\nformat: json\n"), 0644))
+	ts.Require().NoError(os.WriteFile(filepath.Join(chart, ".valet.yaml"), []byte("output: chart.json\n"), 0644))
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", chart})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+	ts.FileExists(filepath.Join(chart, "chart.json"))
+}
+
+// TestConfigShow_Origin ensures config show prints every setting with its origin
+func (ts *ValetTestSuite) TestConfigShow_Origin() {
+	repo, chart := ts.writeRepo()
+	ts.Require().NoError(os.WriteFile(filepath.Join(repo, ".valet.yaml"), []byte("output: repo.json\ntelemetry:\n  headers:\n    api-key: secret\n  tracing:\n    headers:\n      token: secret\n"), 0644))
+	ts.T().Setenv("VALET_STRICT", "warn")
+
+	var out bytes.Buffer
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"config", "show", "--origin", "--debug", chart})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+
+	shown := out.String()
+	ts.Contains(shown, "output: repo.json # file "+filepath.Join(repo, ".valet.yaml"))
+	ts.Contains(shown, "strict: warn # env VALET_STRICT")
+	ts.Contains(shown, "debug: true # flag --debug")
+	ts.Contains(shown, "format: \"\" # default")
+	ts.Contains(shown, "api-key: <redacted>")
+	ts.Contains(shown, "token: <redacted>")
+	ts.NotContains(shown, "secret")
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/mkm29/valet/cmd"
+	"github.com/mkm29/valet/internal/config"
+)
+
+// TestConfig_ApplyEnv ensures VALET_* and OTEL_* variables override the configuration
+func (ts *ValetTestSuite) TestConfig_ApplyEnv() {
+	env := map[string]string{
+		"VALET_CONTEXT":                "charts/app",
+		"VALET_OUTPUT":                 "schema.json",
+		"VALET_DEBUG":                  "true",
+		"VALET_STRICT":                 "warn",
+		"VALET_DRAFT":                  "2020-12",
+		"VALET_TELEMETRY_ENABLED":      "1",
+		"VALET_TELEMETRY_EXPORTER":     "otlp",
+		"OTEL_EXPORTER_OTLP_ENDPOINT":  "https://collector.example.com:4317",
+		"OTEL_EXPORTER_OTLP_HEADERS":   "api-key=s%3Dcret, team=platform",
+		"OTEL_SERVICE_NAME":            "ci-valet",
+		"OTEL_TRACES_SAMPLER_ARG":      "0.25",
+		"VALET_TELEMETRY_SAMPLE_RATE":  "0.5",
+		"VALET_TELEMETRY_SERVICE_NAME": "",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	c := &config.Config{Context: "from-file", Output: "file.json", Telemetry: config.NewTelemetryConfig()}
+	ts.Require().NoError(c.ApplyEnv(lookup))
+	ts.Equal("charts/app", c.Context)
+	ts.Equal("schema.json", c.Output)
+	ts.True(c.Debug)
+	ts.Equal("warn", c.Strict)
+	ts.Equal("2020-12", c.Draft)
+	ts.Equal("env VALET_DRAFT", c.Origin("draft"))
+	ts.True(c.Telemetry.Enabled)
+	ts.Equal("otlp", c.Telemetry.ExporterType)
+	ts.Equal("collector.example.com:4317", c.Telemetry.OTLPEndpoint)
+	ts.False(c.Telemetry.Insecure, "expected https:// to make the connection secure")
+	ts.Equal(map[string]string{"api-key": "s=cret", "team": "platform"}, c.Telemetry.Headers)
+	// Empty variables are ignored
+	ts.Equal("ci-valet", c.Telemetry.ServiceName)
+	// VALET_TELEMETRY_* wins over OTEL_*
+	ts.Equal(0.5, c.Telemetry.SampleRate)
+
+	env = map[string]string{"VALET_DEBUG": "maybe"}
+	err := (&config.Config{}).ApplyEnv(lookup)
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), `invalid VALET_DEBUG "maybe": expected a boolean`)
+}
+
+// TestGenerate_EnvPrecedence ensures flags override env, which overrides the config file
+func (ts *ValetTestSuite) TestGenerate_EnvPrecedence() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("a: 1\n"), 0644))
+	cfgFile := filepath.Join(tmp, "valet.yaml")
+	ts.Require().NoError(os.WriteFile(cfgFile, []byte("output: from-file.json\n"), 0644))
+
+	run := func(args ...string) {
+		rootCmd := cmd.NewRootCmd()
+		rootCmd.SetArgs(append([]string{"generate", "--config-file", cfgFile}, append(args, tmp)...))
+		ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+	}
+
+	run()
+	ts.FileExists(filepath.Join(tmp, "from-file.json"))
+
+	ts.T().Setenv("VALET_OUTPUT", "from-env.json")
+	run()
+	ts.FileExists(filepath.Join(tmp, "from-env.json"))
+
+	run("-o", "from-flag.json")
+	ts.FileExists(filepath.Join(tmp, "from-flag.json"))
+}
@@ -39,7 +39,7 @@ func (ts *ValetTestSuite) TestGenerateCmd_NoValues() {
 	cmd.SetArgs([]string{tmp})
 	err := cmd.Execute()
 	ts.Error(err)
-	ts.Contains(err.Error(), "no values.yaml or values.yml found in")
+	ts.Contains(err.Error(), "no values.yaml, values.yml or values.json found in")
 }
 
 // Test basic schema generation without overrides
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+// TestHook ensures only the charts owning the changed files are regenerated and changes fail the hook
+func (ts *ValetTestSuite) TestHook() {
+	root := ts.T().TempDir()
+	api := ts.writeBatchChart(root, "api", "replicaCount: 1\n")
+	web := ts.writeBatchChart(root, "web", "image: nginx\n")
+	ts.Require().NoError(os.MkdirAll(filepath.Join(api, "templates"), 0755))
+	template := filepath.Join(api, "templates", "deployment.yaml")
+	ts.Require().NoError(os.WriteFile(template, []byte("replicas: {{ .Values.replicaCount }}\n"), 0644))
+
+	hook := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		rootCmd := cmd.NewRootCmd()
+		rootCmd.SetOut(&out)
+		rootCmd.SetArgs(append([]string{"hook"}, args...))
+		err := rootCmd.Execute()
+		return out.String(), err
+	}
+
+	// A template change maps to its chart; files outside of any chart are ignored
+	out, err := hook(template, filepath.Join(root, "README.md"))
+	ts.Require().Error(err, "expected a new schema to fail the hook")
+	ts.Contains(err.Error(), "1 schema(s) updated")
+	ts.Contains(out, "values.schema.json")
+	ts.FileExists(filepath.Join(api, "values.schema.json"))
+	ts.NoFileExists(filepath.Join(web, "values.schema.json"), "expected unrelated charts to be left alone")
+
+	// Once staged, the same files pass
+	_, err = hook(template)
+	ts.NoError(err, "expected an up-to-date schema to pass")
+
+	// --check reports but does not write
+	ts.Require().NoError(os.WriteFile(filepath.Join(api, "values.yaml"), []byte("replicaCount: 1\nimage: nginx\n"), 0644))
+	before, err := os.ReadFile(filepath.Join(api, "values.schema.json"))
+	ts.Require().NoError(err)
+	out, err = hook("--check", filepath.Join(api, "values.yaml"))
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "1 schema(s) out of date")
+	ts.Contains(out, "is out of date")
+	after, err := os.ReadFile(filepath.Join(api, "values.schema.json"))
+	ts.Require().NoError(err)
+	ts.Equal(string(before), string(after))
+
+	// No files is a no-op
+	_, err = hook()
+	ts.NoError(err)
+}
+
+// TestHook_ChartConfig ensures every chart reads the config discovered in its own directory
+func (ts *ValetTestSuite) TestHook_ChartConfig() {
+	root := ts.T().TempDir()
+	api := ts.writeBatchChart(root, "charts/api", "replicaCount: 1\n")
+	web := ts.writeBatchChart(root, "charts/web", "image: nginx\n")
+	ts.Require().NoError(os.WriteFile(filepath.Join(api, ".valet.yaml"), []byte("output: schema.yaml\n"), 0644))
+
+	var out bytes.Buffer
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"hook", filepath.Join(api, "values.yaml"), filepath.Join(web, "values.yaml")})
+	err := rootCmd.Execute()
+	ts.Require().Error(err, "expected new schemas to fail the hook")
+	ts.Contains(err.Error(), "2 schema(s) updated")
+
+	ts.FileExists(filepath.Join(api, "schema.yaml"))
+	ts.NoFileExists(filepath.Join(api, "values.schema.json"))
+	ts.FileExists(filepath.Join(web, "values.schema.json"))
+}
@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mkm29/valet/cmd"
+	"github.com/mkm29/valet/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// writeInitChart creates a chart with a subchart, candidate enums and ambiguous values
+func (ts *ValetTestSuite) writeInitChart() string {
+	dir := ts.T().TempDir()
+	chart := `apiVersion: v2
+name: demo
+version: 0.1.0
+dependencies:
+  - name: postgresql
+    version: 1.0.0
+`
+	values := `# Number of replicas
+replicaCount: 1
+image:
+  repository: nginx
+  pullPolicy: IfNotPresent
+  # Image tag
+  tag: ""
+service:
+  type: ClusterIP
+  ports:
+    - port: 80
+      protocol: TCP
+podAnnotations: {}
+tolerations: []
+resources: ~
+postgresql:
+  enabled: true
+`
+	ts.Require().NoError(os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte(chart), 0644))
+	ts.Require().NoError(os.WriteFile(filepath.Join(dir, "values.yaml"), []byte(values), 0644))
+	return dir
+}
+
+// runInit runs valet init with args and returns its output
+func (ts *ValetTestSuite) runInit(args ...string) (string, error) {
+	var out bytes.Buffer
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs(append([]string{"init"}, args...))
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+// TestInit_WritesConfig ensures valet init writes a valid config tuned to the chart
+func (ts *ValetTestSuite) TestInit_WritesConfig() {
+	dir := ts.writeInitChart()
+	out, err := ts.runInit(dir)
+	ts.Require().NoError(err, "init failed")
+	ts.Contains(out, "Subcharts: postgresql")
+	ts.Contains(out, "Candidate enums: image.pullPolicy, service.type, service.ports[*].protocol")
+	ts.Contains(out, "Values with an ambiguous type: image.tag, podAnnotations, tolerations, resources")
+
+	configPath := filepath.Join(dir, ".valet.yaml")
+	c, err := config.LoadConfig(configPath)
+	ts.Require().NoError(err, "generated config does not load")
+	ts.Require().NoError(c.Validate(), "generated config is invalid")
+	// Settings changing how generate behaves are only suggested
+	ts.Empty(c.Output)
+	ts.Empty(c.Strict)
+	ts.Require().Len(c.Rules, 4)
+	ts.Equal("postgresql", c.Rules[0].Path)
+	ts.Equal("image.pullPolicy", c.Rules[1].Path)
+	ts.Equal([]any{"Always", "IfNotPresent", "Never"}, c.Rules[1].Enum)
+	ts.Equal("service.ports[*].protocol", c.Rules[3].Path)
+
+	data, err := os.ReadFile(configPath)
+	ts.Require().NoError(err)
+	ts.Contains(string(data), "# - path: image.tag")
+	ts.Contains(string(data), "# output: values.schema.json\n")
+	ts.Contains(string(data), "# strict: warn\n")
+
+	// The values file is left untouched without --annotate
+	values, err := os.ReadFile(filepath.Join(dir, "values.yaml"))
+	ts.Require().NoError(err)
+	ts.NotContains(string(values), "@schema")
+
+	// An existing config is only overwritten with --force
+	_, err = ts.runInit(dir)
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "already exists; use --force to overwrite it")
+	_, err = ts.runInit("--force", dir)
+	ts.NoError(err)
+
+	// The enum rules apply to the generated schema
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", dir})
+	ts.Require().NoError(rootCmd.Execute(), "generate failed")
+	schema := ts.readSchema(dir)
+	service := schema["properties"].(map[string]any)["service"].(map[string]any)["properties"].(map[string]any)
+	ts.Equal([]any{"ClusterIP", "NodePort", "LoadBalancer", "ExternalName"}, service["type"].(map[string]any)["enum"])
+}
+
+// TestInit_Annotate ensures --annotate inserts @schema placeholders once, and filled in
+// annotations are applied to the generated schema
+func (ts *ValetTestSuite) TestInit_Annotate() {
+	dir := ts.writeInitChart()
+	valuesPath := filepath.Join(dir, "values.yaml")
+	var before, after map[string]any
+	data, err := os.ReadFile(valuesPath)
+	ts.Require().NoError(err)
+	ts.Require().NoError(yaml.Unmarshal(data, &before))
+
+	out, err := ts.runInit("--annotate", dir)
+	ts.Require().NoError(err, "init failed")
+	ts.Contains(out, "Inserted 4 @schema placeholder(s)")
+
+	data, err = os.ReadFile(valuesPath)
+	ts.Require().NoError(err)
+	ts.Contains(string(data), "  # Image tag\n  # @schema\n  # type:\n  # @schema\n  tag: \"\"\n")
+	ts.Contains(string(data), "# @schema\n# additionalProperties:\n# @schema\npodAnnotations: {}\n")
+	ts.Contains(string(data), "# @schema\n# items:\n# @schema\ntolerations: []\n")
+	ts.Require().NoError(yaml.Unmarshal(data, &after))
+	ts.Equal(before, after, "placeholders must not change the values")
+
+	// Running again keeps the config and the existing placeholders
+	out, err = ts.runInit("--annotate", dir)
+	ts.Require().NoError(err, "init failed")
+	ts.Contains(out, "Kept the existing .valet.yaml")
+	ts.Contains(out, "Inserted 0 @schema placeholder(s)")
+
+	// Empty placeholders are ignored; filled in ones are applied
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", dir})
+	ts.Require().NoError(rootCmd.Execute(), "generate failed")
+
+	filled := strings.Replace(string(data), "  # type:\n", "  # type: string\n  # pattern: ^v?[0-9.]+$\n", 1)
+	filled = strings.Replace(filled, "# additionalProperties:\n", "# additionalProperties:\n#   type: string\n", 1)
+	ts.Require().NoError(os.WriteFile(valuesPath, []byte(filled), 0644))
+	rootCmd = cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", dir})
+	ts.Require().NoError(rootCmd.Execute(), "generate failed")
+	props := ts.readSchema(dir)["properties"].(map[string]any)
+	tag := props["image"].(map[string]any)["properties"].(map[string]any)["tag"].(map[string]any)
+	ts.Equal("string", tag["type"])
+	ts.Equal("^v?[0-9.]+$", tag["pattern"])
+	ts.Equal(map[string]any{"type": "string"}, props["podAnnotations"].(map[string]any)["additionalProperties"])
+}
+
+// TestGenerate_MalformedAnnotations ensures unterminated and invalid @schema blocks are skipped
+// without failing the generation, while the valid blocks are still applied
+func (ts *ValetTestSuite) TestGenerate_MalformedAnnotations() {
+	dir := ts.T().TempDir()
+	values := "# @schema\n# type: [string\n# @schema\nname: \"\"\n" +
+		"# @schema\n# pattern: ^v\n# @schema\nversion: \"\"\n" +
+		"# @schema\n# type: string\ntag: \"\"\n"
+	ts.Require().NoError(os.WriteFile(filepath.Join(dir, "values.yaml"), []byte(values), 0644))
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", dir})
+	ts.Require().NoError(rootCmd.Execute(), "generate failed")
+
+	props := ts.readSchema(dir)["properties"].(map[string]any)
+	ts.Equal("^v", props["version"].(map[string]any)["pattern"])
+	// The skipped blocks leave the inferred schemas
+	ts.NotContains(props["name"], "pattern")
+	ts.Equal(props["name"].(map[string]any)["type"], props["tag"].(map[string]any)["type"])
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+// readSchema reads and decodes the generated values.schema.json in dir
+func (ts *ValetTestSuite) readSchema(dir string) map[string]any {
+	data, err := os.ReadFile(filepath.Join(dir, "values.schema.json"))
+	ts.Require().NoError(err, "expected values.schema.json to be written")
+	var schema map[string]any
+	ts.Require().NoError(json.Unmarshal(data, &schema))
+	return schema
+}
+
+// TestGenerate_ValuesJSON ensures values.json is used when there is no YAML values file
+func (ts *ValetTestSuite) TestGenerate_ValuesJSON() {
+	tmp := ts.T().TempDir()
+	values := "{\n\t\"replicaCount\": 2,\n\t\"image\": {\"repository\": \"nginx\"},\n\t\"ratio\": 0.5\n}\n"
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.json"), []byte(values), 0644))
+
+	msg, err := cmd.Generate(tmp, "")
+	ts.Require().NoError(err, "Generate failed")
+	ts.Contains(msg, "from values.json")
+
+	props := ts.readSchema(tmp)["properties"].(map[string]any)
+	ts.Equal("integer", props["replicaCount"].(map[string]any)["type"])
+	ts.Equal("number", props["ratio"].(map[string]any)["type"])
+	ts.Equal("object", props["image"].(map[string]any)["type"])
+}
+
+// TestGenerate_MultiDocument ensures later YAML documents are merged over earlier ones
+func (ts *ValetTestSuite) TestGenerate_MultiDocument() {
+	tmp := ts.T().TempDir()
+	values := "image:\n  repository: nginx\n  tag: \"1.0\"\n---\n---\nimage:\n  tag: \"2.0\"\nreplicaCount: 3\n"
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte(values), 0644))
+
+	_, err := cmd.Generate(tmp, "")
+	ts.Require().NoError(err, "Generate failed")
+
+	schema := ts.readSchema(tmp)
+	image := schema["properties"].(map[string]any)["image"].(map[string]any)
+	ts.Equal("nginx", image["properties"].(map[string]any)["repository"].(map[string]any)["default"])
+	ts.Equal("2.0", image["properties"].(map[string]any)["tag"].(map[string]any)["default"])
+	ts.Contains(schema["properties"], "replicaCount")
+}
+
+// TestGenerate_NonMapDocument ensures a top-level document that is not a mapping is rejected
+func (ts *ValetTestSuite) TestGenerate_NonMapDocument() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("a: 1\n---\n- b\n- c\n"), 0644))
+	_, err := cmd.Generate(tmp, "")
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "document 2 is a list, expected a mapping of values")
+
+	jsonDir := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(jsonDir, "values.json"), []byte(`"oops"`), 0644))
+	_, err = cmd.Generate(jsonDir, "")
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "top-level value is a string, expected an object of values")
+}
+
+// TestGenerate_ValuesFlag ensures --values selects a values file with any name
+func (ts *ValetTestSuite) TestGenerate_ValuesFlag() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("fromDefault: true\n"), 0644))
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "defaults.yaml"), []byte("fromFlag: true\n"), 0644))
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "--values", "defaults.yaml", tmp})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+
+	props := ts.readSchema(tmp)["properties"].(map[string]any)
+	ts.Contains(props, "fromFlag")
+	ts.NotContains(props, "fromDefault")
+
+	rootCmd = cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "--values", "missing.yaml", tmp})
+	err := rootCmd.Execute()
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "missing.yaml not found")
+}
@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+func (ts *ValetTestSuite) TestNewLintCmd() {
+	cmd := cmd.NewLintCmd()
+	ts.Equal("lint <context-dir>", cmd.Use, "expected Use 'lint <context-dir>'")
+	ts.NotEmpty(cmd.Short, "expected non-empty Short description")
+	ts.NotNil(cmd.Args, "expected Args validator to be set")
+}
+
+// writeChart writes a chart with the given values and templates to a temporary directory
+func (ts *ValetTestSuite) writeChart(values string, templates map[string]string) string {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "Chart.yaml"), []byte("apiVersion: v2\nname: demo\nversion: 0.1.0\n"), 0644))
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte(values), 0644))
+	ts.Require().NoError(os.Mkdir(filepath.Join(tmp, "templates"), 0755))
+	for name, content := range templates {
+		ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "templates", name), []byte(content), 0644))
+	}
+	return tmp
+}
+
+// TestLint_UnusedValues ensures values not read by any template are reported once, at the topmost key
+func (ts *ValetTestSuite) TestLint_UnusedValues() {
+	values := `image:
+  repository: nginx
+  digest: ""
+resources:
+  limits:
+    cpu: 100m
+servers:
+  - name: a
+    weight: 1
+legacy:
+  enabled: true
+  port: 8080
+global:
+  region: eu
+`
+	chart := ts.writeChart(values, map[string]string{
+		"_helpers.tpl": `{{- define "demo.server" -}}
+name: {{ .name }}
+{{- end }}
+`,
+		"deployment.yaml": `image: {{ .Values.image.repository }}
+resources:
+  {{- toYaml .Values.resources | nindent 2 }}
+servers:
+{{- range .Values.servers }}
+  - {{ include "demo.server" . }}
+{{- end }}
+`,
+	})
+
+	lintCmd := cmd.NewLintCmd()
+	var out bytes.Buffer
+	lintCmd.SetOut(&out)
+	lintCmd.SetErr(new(bytes.Buffer))
+	lintCmd.SetArgs([]string{chart})
+	err := lintCmd.Execute()
+	ts.Require().Error(err, "expected lint to fail")
+	ts.Contains(err.Error(), "found 3 problem(s)")
+
+	output := out.String()
+	ts.Contains(output, "values.yaml:3:3: warning: image.digest is not used by any template or subchart (unused-value)")
+	ts.Contains(output, "values.yaml:9:5: warning: servers[*].weight is not used")
+	ts.Contains(output, "values.yaml:10:1: warning: legacy is not used")
+	ts.NotContains(output, "legacy.port is not used")
+	ts.NotContains(output, "resources.limits is not used")
+	ts.NotContains(output, "global.region is not used")
+}
+
+// TestLint_ExampleChart ensures the example chart uses all of its values
+func (ts *ValetTestSuite) TestLint_ExampleChart() {
+	lintCmd := cmd.NewLintCmd()
+	var out bytes.Buffer
+	lintCmd.SetOut(&out)
+	lintCmd.SetArgs([]string{filepath.Join("..", "testdata", "mychart")})
+	ts.NoError(lintCmd.Execute(), "unexpected findings: %s", out.String())
+}
+
+// lintRulesValues triggers every node-level rule once
+const lintRulesValues = `# Documented
+name: demo
+# Duplicate
+name: other
+# Ports
+ports:
+  - 80
+  - "443"
+# Null
+nodeName: "null"
+# Flag
+enabled: "true"
+# Version
+version: 1.10
+# Mode
+mode: 0755
+count: 3
+`
+
+// TestLint_Rules ensures each consistency rule reports its finding as JSON
+func (ts *ValetTestSuite) TestLint_Rules() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte(lintRulesValues), 0644))
+
+	lintCmd := cmd.NewLintCmd()
+	var out bytes.Buffer
+	lintCmd.SetOut(&out)
+	lintCmd.SetErr(new(bytes.Buffer))
+	lintCmd.SetArgs([]string{"--report-format", "json", tmp})
+	err := lintCmd.Execute()
+	ts.Require().Error(err, "expected lint to fail")
+	ts.Contains(err.Error(), "found 6 problem(s)")
+
+	var findings []map[string]any
+	ts.Require().NoError(json.Unmarshal(out.Bytes(), &findings), "invalid JSON: %s", out.String())
+	byRule := make(map[string]map[string]any)
+	for _, f := range findings {
+		byRule[f["rule"].(string)] = f
+	}
+	ts.Len(byRule, 6)
+	ts.Equal(float64(4), byRule["duplicate-key"]["line"])
+	ts.Equal("error", byRule["duplicate-key"]["severity"])
+	ts.Contains(byRule["duplicate-key"]["message"], "already defined at line 2")
+	ts.Equal("ports[*]", byRule["mixed-types"]["path"])
+	ts.Equal("nodeName", byRule["null-string"]["path"])
+	ts.Equal("enabled", byRule["boolean-string"]["path"])
+	ts.Equal("count", byRule["undocumented-key"]["path"])
+	ts.Equal("info", byRule["undocumented-key"]["severity"])
+
+	var ambiguous []string
+	for _, f := range findings {
+		if f["rule"] == "ambiguous-number" {
+			ambiguous = append(ambiguous, f["message"].(string))
+		}
+	}
+	ts.Len(ambiguous, 2)
+	ts.Contains(ambiguous[0], "1.10 is parsed as the float 1.1")
+	ts.Contains(ambiguous[1], "0755 is parsed as an octal number")
+}
+
+// TestLint_SARIF ensures findings are reported as a SARIF log
+func (ts *ValetTestSuite) TestLint_SARIF() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("# Flag\nenabled: \"false\"\n"), 0644))
+
+	lintCmd := cmd.NewLintCmd()
+	var out bytes.Buffer
+	lintCmd.SetOut(&out)
+	lintCmd.SetErr(new(bytes.Buffer))
+	lintCmd.SetArgs([]string{"--report-format", "sarif", tmp})
+	ts.Require().Error(lintCmd.Execute())
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	ts.Require().NoError(json.Unmarshal(out.Bytes(), &log), "invalid SARIF: %s", out.String())
+	ts.Equal("2.1.0", log.Version)
+	ts.Require().Len(log.Runs, 1)
+	ts.Equal("valet", log.Runs[0].Tool.Driver.Name)
+	ts.NotEmpty(log.Runs[0].Tool.Driver.Rules)
+	ts.Require().Len(log.Runs[0].Results, 1)
+	ts.Equal("boolean-string", log.Runs[0].Results[0].RuleID)
+	ts.Equal("warning", log.Runs[0].Results[0].Level)
+	ts.Equal(2, log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region.StartLine)
+}
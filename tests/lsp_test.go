@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+func (ts *ValetTestSuite) TestNewLSPCmd() {
+	cmd := cmd.NewLSPCmd()
+	ts.Equal("lsp", cmd.Use, "expected Use 'lsp'")
+	ts.NotEmpty(cmd.Short, "expected non-empty Short description")
+	ts.NotNil(cmd.RunE, "expected RunE function to be set")
+}
+
+// TestLSPCmd_Diagnostics drives the language server over stdio for a document in a chart
+func (ts *ValetTestSuite) TestLSPCmd_Diagnostics() {
+	tmp := ts.T().TempDir()
+	err := os.WriteFile(filepath.Join(tmp, "Chart.yaml"), []byte("name: demo\nversion: 0.1.0\n"), 0644)
+	ts.Require().NoError(err, "failed to write Chart.yaml")
+	err = os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("replicaCount: 1\n"), 0644)
+	ts.Require().NoError(err, "failed to write values.yaml")
+
+	uri := "file://" + filepath.ToSlash(filepath.Join(tmp, "values-prod.yaml"))
+	var in bytes.Buffer
+	for _, msg := range []map[string]any{
+		{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{}},
+		{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri, "text": "replicaCount: many\n"},
+		}},
+		{"jsonrpc": "2.0", "method": "exit"},
+	} {
+		body, err := json.Marshal(msg)
+		ts.Require().NoError(err)
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	lspCmd := cmd.NewLSPCmd()
+	var out bytes.Buffer
+	lspCmd.SetIn(&in)
+	lspCmd.SetOut(&out)
+	lspCmd.SetArgs([]string{})
+	err = lspCmd.Execute()
+	ts.Require().NoError(err, "lsp command failed")
+
+	output := out.String()
+	ts.Contains(output, `"serverInfo":{"name":"valet"`)
+	ts.Contains(output, "textDocument/publishDiagnostics")
+	ts.True(strings.Contains(output, "replicaCount: expected integer, got string"), "expected type diagnostic, got %s", output)
+}
+
+// runLSP runs the language server over the given raw input, followed by an exit notification,
+// and returns its output
+func (ts *ValetTestSuite) runLSP(input string) string {
+	exit := `{"jsonrpc":"2.0","method":"exit"}`
+	in := bytes.NewBufferString(input + fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(exit), exit))
+	lspCmd := cmd.NewLSPCmd()
+	var out bytes.Buffer
+	lspCmd.SetIn(in)
+	lspCmd.SetOut(&out)
+	lspCmd.SetArgs([]string{})
+	ts.Require().NoError(lspCmd.Execute(), "lsp command failed")
+	return out.String()
+}
+
+// TestLSPCmd_ParseError ensures an unparsable request gets a parse error with a null id
+func (ts *ValetTestSuite) TestLSPCmd_ParseError() {
+	body := `{"jsonrpc":`
+	output := ts.runLSP(fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body))
+	ts.Contains(output, `"id":null`)
+	ts.Contains(output, `"code":-32700`)
+}
+
+// TestLSPCmd_FramingError ensures messages with a missing or invalid Content-Length are
+// skipped and the server keeps answering the following requests
+func (ts *ValetTestSuite) TestLSPCmd_FramingError() {
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	output := ts.runLSP("Content-Type: application/vscode-jsonrpc\r\n\r\n" +
+		"Content-Length: many\r\n\r\n" +
+		"Content-Length: -1\r\n\r\n" +
+		fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(initialize), initialize))
+	ts.Contains(output, `"id":1`)
+	ts.Contains(output, `"serverInfo":{"name":"valet"`)
+	ts.NotContains(output, `"error"`)
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+// TestGenerate_SchemaOverlay ensures overlay fragments are merged onto the inferred schema
+func (ts *ValetTestSuite) TestGenerate_SchemaOverlay() {
+	tmp := ts.T().TempDir()
+	values := []byte(
+		"replicaCount: 1\n" +
+			"image:\n" +
+			"  tag: latest\n" +
+			"hosts:\n" +
+			"  - host: example.com\n",
+	)
+	err := os.WriteFile(filepath.Join(tmp, "values.yaml"), values, 0644)
+	ts.Require().NoError(err, "failed to write values.yaml")
+
+	overlay := []byte(
+		"replicaCount:\n" +
+			"  minimum: 1\n" +
+			"image.tag:\n" +
+			"  pattern: \"^v?[0-9.]+$|^latest$\"\n" +
+			"hosts[*].host:\n" +
+			"  format: hostname\n" +
+			"extra:\n" +
+			"  type: string\n" +
+			"  description: declared only in the overlay\n",
+	)
+	err = os.WriteFile(filepath.Join(tmp, "values.schema.overlay.yaml"), overlay, 0644)
+	ts.Require().NoError(err, "failed to write overlay")
+
+	_, err = cmd.Generate(tmp, "")
+	ts.Require().NoError(err, "Generate failed")
+
+	data, err := os.ReadFile(filepath.Join(tmp, "values.schema.json"))
+	ts.Require().NoError(err, "failed to read schema")
+
+	var schema map[string]any
+	ts.Require().NoError(json.Unmarshal(data, &schema), "invalid JSON schema")
+
+	props := schema["properties"].(map[string]any)
+
+	replicas := props["replicaCount"].(map[string]any)
+	ts.Equal("integer", replicas["type"], "inferred type should be kept")
+	ts.Equal(float64(1), replicas["minimum"], "overlay minimum missing")
+
+	image := props["image"].(map[string]any)
+	tag := image["properties"].(map[string]any)["tag"].(map[string]any)
+	ts.Equal("string", tag["type"])
+	ts.Equal("^v?[0-9.]+$|^latest$", tag["pattern"], "overlay pattern missing")
+
+	hosts := props["hosts"].(map[string]any)
+	host := hosts["items"].(map[string]any)["properties"].(map[string]any)["host"].(map[string]any)
+	ts.Equal("hostname", host["format"], "overlay format on array items missing")
+
+	extra := props["extra"].(map[string]any)
+	ts.Equal("declared only in the overlay", extra["description"])
+}
+
+// TestGenerate_SchemaOverlayInvalidEntry ensures non-mapping fragments are rejected
+func (ts *ValetTestSuite) TestGenerate_SchemaOverlayInvalidEntry() {
+	tmp := ts.T().TempDir()
+	err := os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("foo: bar\n"), 0644)
+	ts.Require().NoError(err, "failed to write values.yaml")
+	err = os.WriteFile(filepath.Join(tmp, "values.schema.overlay.yaml"), []byte("foo: string\n"), 0644)
+	ts.Require().NoError(err, "failed to write overlay")
+
+	_, err = cmd.Generate(tmp, "")
+	ts.Error(err)
+	ts.Contains(err.Error(), "must be a mapping")
+}
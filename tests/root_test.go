@@ -64,7 +64,7 @@ func (ts *ValetTestSuite) TestRootCmd_NoValues() {
 	rootCmd.SetArgs([]string{})
 	err := rootCmd.Execute()
 	ts.Error(err)
-	ts.Contains(err.Error(), "no values.yaml or values.yml found in", "expected missing values error")
+	ts.Contains(err.Error(), "no values.yaml, values.yml or values.json found in", "expected missing values error")
 }
 
 // TestRootCmd_ServiceVersionMatchesBuildInfo tests that the telemetry service version
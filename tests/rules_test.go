@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+// TestGenerate_Rules ensures path-scoped rules from the config file adjust the inferred schema
+func (ts *ValetTestSuite) TestGenerate_Rules() {
+	repo, chart := ts.writeRepo()
+	values := `replicaCount: 1
+image:
+  tag: stable
+debug: false
+api:
+  resources: {}
+worker:
+  resources: {}
+ingress:
+  hosts:
+    - host: example.com
+`
+	ts.Require().NoError(os.WriteFile(filepath.Join(chart, "values.yaml"), []byte(values), 0644))
+	config := `rules:
+  - path: "*.resources"
+    description: Container resources
+  - path: ingress.hosts[*].host
+    format: hostname
+  - path: image.tag
+    enum: [stable, edge]
+  - path: debug
+    ignore: true
+  - path: replicaCount
+    required: false
+    type: integer
+`
+	ts.Require().NoError(os.WriteFile(filepath.Join(repo, ".valet.yaml"), []byte(config), 0644))
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", chart})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+
+	schema := ts.readSchema(chart)
+	props := schema["properties"].(map[string]any)
+	ts.NotContains(props, "debug")
+	ts.NotContains(schema["required"], "debug")
+	ts.NotContains(schema["required"], "replicaCount")
+	ts.Equal("Container resources", props["api"].(map[string]any)["properties"].(map[string]any)["resources"].(map[string]any)["description"])
+	ts.Equal("Container resources", props["worker"].(map[string]any)["properties"].(map[string]any)["resources"].(map[string]any)["description"])
+	tag := props["image"].(map[string]any)["properties"].(map[string]any)["tag"].(map[string]any)
+	ts.Equal([]any{"stable", "edge"}, tag["enum"])
+	host := props["ingress"].(map[string]any)["properties"].(map[string]any)["hosts"].(map[string]any)["items"].(map[string]any)["properties"].(map[string]any)["host"].(map[string]any)
+	ts.Equal("hostname", host["format"])
+}
+
+// TestGenerate_ChartSection ensures charts entries set the output, draft and rules of their chart
+func (ts *ValetTestSuite) TestGenerate_ChartSection() {
+	repo, chart := ts.writeRepo()
+	other := filepath.Join(repo, "charts", "other")
+	ts.Require().NoError(os.MkdirAll(other, 0755))
+	ts.Require().NoError(os.WriteFile(filepath.Join(other, "values.yaml"), []byte("a: 1\n"), 0644))
+	ts.Require().NoError(os.WriteFile(filepath.Join(chart, "prod.yaml"), []byte("b: true\n"), 0644))
+	config := `draft: draft-07
+charts:
+  - context: charts/app
+    output: app.schema.json
+    overrides: prod.yaml
+    draft: "2020-12"
+    rules:
+      - path: a
+        description: From the chart section
+`
+	ts.Require().NoError(os.WriteFile(filepath.Join(repo, ".valet.yaml"), []byte(config), 0644))
+
+	for _, dir := range []string{chart, other} {
+		rootCmd := cmd.NewRootCmd()
+		rootCmd.SetArgs([]string{"generate", dir})
+		ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+	}
+
+	data, err := os.ReadFile(filepath.Join(chart, "app.schema.json"))
+	ts.Require().NoError(err, "expected the chart output to be used")
+	var schema map[string]any
+	ts.Require().NoError(json.Unmarshal(data, &schema))
+	ts.Equal("https://json-schema.org/draft/2020-12/schema", schema["$schema"])
+	props := schema["properties"].(map[string]any)
+	ts.Contains(props, "b", "expected the chart overrides to be merged")
+	ts.Equal("From the chart section", props["a"].(map[string]any)["description"])
+
+	otherSchema := ts.readSchema(other)
+	ts.Equal("http://json-schema.org/draft-07/schema#", otherSchema["$schema"])
+	ts.NotContains(otherSchema["properties"].(map[string]any)["a"], "description")
+
+	// Flags override the chart section
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "-o", "flag.json", chart})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+	ts.FileExists(filepath.Join(chart, "flag.json"))
+}
+
+// TestGenerate_InvalidDraft ensures unsupported drafts are rejected
+func (ts *ValetTestSuite) TestGenerate_InvalidDraft() {
+	_, chart := ts.writeRepo()
+	ts.Require().NoError(os.WriteFile(filepath.Join(chart, ".valet.yaml"), []byte("draft: draft-03\n"), 0644))
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", chart})
+	err := rootCmd.Execute()
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), `unsupported schema draft "draft-03"`)
+}
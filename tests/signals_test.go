@@ -0,0 +1,361 @@
+package tests
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mkm29/valet/cmd"
+	"github.com/mkm29/valet/internal/config"
+)
+
+// TestTelemetry_SignalExporters ensures the tracing and metrics settings fall back to the
+// telemetry settings
+func (ts *ValetTestSuite) TestTelemetry_SignalExporters() {
+	content := `telemetry:
+  enabled: true
+  exporterType: otlp
+  otlpEndpoint: collector:4317
+  insecure: true
+  headers:
+    team: platform
+  sampleRate: 0.5
+  tracing:
+    headers:
+      team: tracing
+      api-key: secret
+    sampleRate: 0.1
+    exportInterval: 2
+  metrics:
+    exporter: stdout
+    endpoint: metrics:4317
+    insecure: false
+    exportInterval: 10
+`
+	cfgFile := filepath.Join(ts.T().TempDir(), "valet.yaml")
+	ts.Require().NoError(os.WriteFile(cfgFile, []byte(content), 0644))
+	c, err := config.LoadConfig(cfgFile)
+	ts.Require().NoError(err)
+	ts.Require().NoError(c.Validate())
+	t := c.Telemetry
+
+	ts.Equal(config.Exporter{
+		Type:     "otlp",
+		Endpoint: "collector:4317",
+		Insecure: true,
+		Headers:  map[string]string{"team": "tracing", "api-key": "secret"},
+		Interval: 2 * time.Second,
+		FilePath: config.DefaultFilePath,
+	}, t.TracesExporter())
+	ts.Equal(0.1, t.TraceSampleRate())
+	ts.Equal(config.Exporter{
+		Type:     "stdout",
+		Endpoint: "metrics:4317",
+		Insecure: false,
+		Headers:  map[string]string{"team": "platform"},
+		Interval: 10 * time.Second,
+		FilePath: config.DefaultFilePath,
+		Prometheus: config.PrometheusConfig{
+			Listen:   config.DefaultPrometheusListen,
+			Textfile: config.DefaultPrometheusTextfile,
+		},
+	}, t.MetricsExporter())
+	ts.Equal("file "+cfgFile, c.Origin("telemetry.metrics.exporter"))
+
+	// Without sub-configs, both signals use the telemetry settings and default intervals
+	defaults := config.NewTelemetryConfig()
+	ts.Equal("none", defaults.TracesExporter().Type)
+	ts.Equal(config.DefaultTraceInterval, defaults.TracesExporter().Interval)
+	ts.Equal(config.DefaultMetricInterval, defaults.MetricsExporter().Interval)
+	ts.Equal(1.0, defaults.TraceSampleRate())
+
+	// A disabled signal is not exported
+	disabled := false
+	t.Metrics.Enabled = &disabled
+	ts.Equal("none", t.MetricsExporter().Type)
+}
+
+// TestTelemetry_FileExporter ensures the file of each signal falls back to the telemetry file
+func (ts *ValetTestSuite) TestTelemetry_FileExporter() {
+	env := map[string]string{
+		"VALET_TELEMETRY_EXPORTER":                "file",
+		"VALET_TELEMETRY_FILE_PATH":               "ci/telemetry.jsonl",
+		"VALET_TELEMETRY_METRICS_FILE_PATH":       "ci/metrics.jsonl",
+		"VALET_TELEMETRY_TRACING_EXPORT_INTERVAL": "1",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	c := &config.Config{Telemetry: config.NewTelemetryConfig()}
+	ts.Require().NoError(c.ApplyEnv(lookup))
+	ts.Require().NoError(c.Validate())
+	ts.Equal("file", c.Telemetry.TracesExporter().Type)
+	ts.Equal("ci/telemetry.jsonl", c.Telemetry.TracesExporter().FilePath)
+	ts.Equal("ci/metrics.jsonl", c.Telemetry.MetricsExporter().FilePath)
+	ts.Equal("env VALET_TELEMETRY_METRICS_FILE_PATH", c.Origin("telemetry.metrics.filePath"))
+}
+
+// TestTelemetry_DefaultEndpoint ensures exporters without an endpoint send to the local
+// collector's port of their protocol
+func (ts *ValetTestSuite) TestTelemetry_DefaultEndpoint() {
+	t := config.NewTelemetryConfig()
+	t.ExporterType = "otlp"
+	ts.Equal(config.DefaultOTLPEndpoint, t.TracesExporter().Endpoint)
+
+	t.ExporterType = "otlphttp"
+	ts.Equal("localhost:4318", t.TracesExporter().Endpoint)
+	ts.Equal("localhost:4318", t.LogsExporter().Endpoint)
+
+	// A signal switching to OTLP/HTTP uses the HTTP port too
+	t.ExporterType = "otlp"
+	t.Metrics.Exporter = "otlphttp"
+	ts.Equal(config.DefaultOTLPEndpoint, t.TracesExporter().Endpoint)
+	ts.Equal(config.DefaultOTLPHTTPEndpoint, t.MetricsExporter().Endpoint)
+
+	// An endpoint that was set is kept, whatever the protocol
+	t.OTLPEndpoint = "localhost:4317"
+	ts.Equal("localhost:4317", t.MetricsExporter().Endpoint)
+}
+
+// TestTelemetry_PrometheusExporter ensures the prometheus exporter is configured for metrics only
+func (ts *ValetTestSuite) TestTelemetry_PrometheusExporter() {
+	env := map[string]string{
+		"OTEL_METRICS_EXPORTER":                       "prometheus",
+		"OTEL_EXPORTER_PROMETHEUS_PORT":               "9100",
+		"VALET_TELEMETRY_METRICS_PROMETHEUS_TEXTFILE": "/var/lib/node_exporter/textfile/valet.prom",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	c := &config.Config{Telemetry: config.NewTelemetryConfig()}
+	ts.Require().NoError(c.ApplyEnv(lookup))
+	ts.Require().NoError(c.Validate())
+	metrics := c.Telemetry.MetricsExporter()
+	ts.Equal("prometheus", metrics.Type)
+	ts.Equal(config.PrometheusConfig{
+		Listen:   "localhost:9100",
+		Textfile: "/var/lib/node_exporter/textfile/valet.prom",
+	}, metrics.Prometheus)
+	ts.Equal("none", c.Telemetry.TracesExporter().Type)
+
+	// Defaults
+	defaults := config.NewTelemetryConfig().MetricsExporter().Prometheus
+	ts.Equal(config.DefaultPrometheusListen, defaults.Listen)
+	ts.Equal(config.DefaultPrometheusTextfile, defaults.Textfile)
+
+	// Traces cannot be exported to Prometheus
+	c.Telemetry.ExporterType = "prometheus"
+	err := c.Validate()
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "invalid exporter type: prometheus (it only exports metrics; set metrics.exporter)")
+	c.Telemetry.ExporterType = "none"
+	c.Telemetry.Tracing.Exporter = "prometheus"
+	err = c.Validate()
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "telemetry: tracing: invalid exporter type: prometheus")
+
+	env = map[string]string{"OTEL_TRACES_EXPORTER": "prometheus"}
+	err = (&config.Config{}).ApplyEnv(lookup)
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), `invalid OTEL_TRACES_EXPORTER "prometheus": expected otlp, console or none`)
+}
+
+// TestTelemetry_LogsExporter ensures logs are configured like the other signals, with a level
+func (ts *ValetTestSuite) TestTelemetry_LogsExporter() {
+	env := map[string]string{
+		"VALET_TELEMETRY_EXPORTER_TYPE":        "otlp",
+		"VALET_TELEMETRY_LOGS_EXPORTER":        "otlphttp",
+		"OTEL_EXPORTER_OTLP_LOGS_ENDPOINT":     "http://collector:4318/custom/logs",
+		"VALET_TELEMETRY_LOGS_LEVEL":           "warn",
+		"VALET_TELEMETRY_TRACING_EXPORTER":     "none",
+		"VALET_TELEMETRY_LOGS_EXPORT_INTERVAL": "2",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	c := &config.Config{Telemetry: config.NewTelemetryConfig()}
+	ts.Require().NoError(c.ApplyEnv(lookup))
+	ts.Require().NoError(c.Validate())
+	logs := c.Telemetry.LogsExporter()
+	ts.Equal("otlphttp", logs.Type)
+	ts.Equal("collector:4318", logs.Endpoint)
+	ts.Equal("/custom/logs", logs.URLPath)
+	ts.Equal(2*time.Second, logs.Interval)
+	ts.Equal("warn", c.Telemetry.LogLevel())
+	ts.Equal("none", c.Telemetry.TracesExporter().Type)
+
+	// Defaults
+	defaults := config.NewTelemetryConfig()
+	ts.Equal(config.DefaultLogInterval, defaults.LogsExporter().Interval)
+	ts.Equal(config.DefaultLogLevel, defaults.LogLevel())
+
+	c.Telemetry.Logs.Level = "trace"
+	err := c.Validate()
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "logs: invalid level: trace")
+	c.Telemetry.Logs.Level = ""
+	c.Telemetry.Logs.Exporter = "prometheus"
+	err = c.Validate()
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "logs: invalid exporter type: prometheus")
+}
+
+// TestTelemetry_SignalEnv ensures the per-signal environment variables are read
+func (ts *ValetTestSuite) TestTelemetry_SignalEnv() {
+	env := map[string]string{
+		"OTEL_TRACES_EXPORTER":                    "otlp",
+		"OTEL_METRICS_EXPORTER":                   "console",
+		"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT":      "http://traces.example.com:4317",
+		"OTEL_EXPORTER_OTLP_METRICS_HEADERS":      "api-key=secret",
+		"VALET_TELEMETRY_TRACING_SAMPLE_RATE":     "0.2",
+		"VALET_TELEMETRY_METRICS_ENABLED":         "false",
+		"VALET_TELEMETRY_METRICS_EXPORTER":        "otlp",
+		"VALET_TELEMETRY_METRICS_EXPORT_INTERVAL": "15",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	c := &config.Config{Telemetry: config.NewTelemetryConfig()}
+	ts.Require().NoError(c.ApplyEnv(lookup))
+	tracing, metrics := c.Telemetry.Tracing, c.Telemetry.Metrics
+	ts.Equal("otlp", tracing.Exporter)
+	ts.Equal("traces.example.com:4317", tracing.Endpoint)
+	ts.Require().NotNil(tracing.Insecure)
+	ts.True(*tracing.Insecure, "expected http:// to make the connection insecure")
+	ts.Require().NotNil(tracing.SampleRate)
+	ts.Equal(0.2, *tracing.SampleRate)
+	// VALET_TELEMETRY_METRICS_* wins over OTEL_*
+	ts.Equal("otlp", metrics.Exporter)
+	ts.Equal(map[string]string{"api-key": "secret"}, metrics.Headers)
+	ts.Equal(15, metrics.ExportInterval)
+	ts.Require().NotNil(metrics.Enabled)
+	ts.False(*metrics.Enabled)
+	ts.Equal("env VALET_TELEMETRY_METRICS_EXPORTER", c.Origin("telemetry.metrics.exporter"))
+
+	env = map[string]string{"OTEL_TRACES_EXPORTER": "zipkin"}
+	err := (&config.Config{}).ApplyEnv(lookup)
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), `invalid OTEL_TRACES_EXPORTER "zipkin": expected otlp, console or none`)
+}
+
+// TestTelemetry_TransportEnv ensures the OTLP transport settings are read from the environment
+// and resolved per signal
+func (ts *ValetTestSuite) TestTelemetry_TransportEnv() {
+	env := map[string]string{
+		"OTEL_EXPORTER_OTLP_COMPRESSION":                "gzip",
+		"OTEL_EXPORTER_OTLP_TIMEOUT":                    "2500",
+		"OTEL_EXPORTER_OTLP_CERTIFICATE":                "/etc/otel/ca.pem",
+		"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT":           "https://metrics.example.com:4318/otlp/v1/metrics",
+		"VALET_TELEMETRY_EXPORTER":                      "otlphttp",
+		"VALET_TELEMETRY_TLS_CERT_FILE":                 "/etc/otel/client.pem",
+		"VALET_TELEMETRY_TLS_KEY_FILE":                  "/etc/otel/client-key.pem",
+		"VALET_TELEMETRY_TRACING_COMPRESSION":           "none",
+		"VALET_TELEMETRY_TRACING_TIMEOUT":               "20",
+		"VALET_TELEMETRY_TRACING_TLS_CA_FILE":           "/etc/otel/traces-ca.pem",
+		"VALET_TELEMETRY_TRACING_URL_PATH":              "/otlp/v1/traces",
+		"VALET_TELEMETRY_METRICS_TLS_CERT_FILE":         "/etc/otel/metrics.pem",
+		"VALET_TELEMETRY_METRICS_TLS_KEY_FILE":          "/etc/otel/metrics-key.pem",
+		"OTEL_EXPORTER_OTLP_METRICS_CLIENT_KEY":         "/etc/otel/otel-metrics.pem",
+		"OTEL_EXPORTER_OTLP_METRICS_CLIENT_CERTIFICATE": "/etc/otel/otel-metrics.pem",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	c := &config.Config{Telemetry: config.NewTelemetryConfig()}
+	ts.Require().NoError(c.ApplyEnv(lookup))
+	ts.Require().NoError(c.Validate())
+	t := c.Telemetry
+	ts.Equal(3, t.Timeout, "expected the milliseconds of OTEL_EXPORTER_OTLP_TIMEOUT to round up to seconds")
+
+	traces := t.TracesExporter()
+	ts.Equal("otlphttp", traces.Type)
+	ts.Equal("/otlp/v1/traces", traces.URLPath)
+	ts.Equal("none", traces.Compression)
+	ts.Equal(20*time.Second, traces.Timeout)
+	ts.Equal(config.TLSConfig{
+		CAFile:   "/etc/otel/traces-ca.pem",
+		CertFile: "/etc/otel/client.pem",
+		KeyFile:  "/etc/otel/client-key.pem",
+	}, traces.TLS)
+
+	metrics := t.MetricsExporter()
+	ts.Equal("metrics.example.com:4318", metrics.Endpoint)
+	ts.Equal("/otlp/v1/metrics", metrics.URLPath)
+	ts.Equal("gzip", metrics.Compression)
+	ts.Equal(3*time.Second, metrics.Timeout)
+	ts.Equal(config.TLSConfig{
+		CAFile:   "/etc/otel/ca.pem",
+		CertFile: "/etc/otel/metrics.pem",
+		KeyFile:  "/etc/otel/metrics-key.pem",
+	}, metrics.TLS)
+	ts.Equal("env VALET_TELEMETRY_TRACING_TLS_CA_FILE", c.Origin("telemetry.tracing.tls.caFile"))
+
+	c = &config.Config{Telemetry: config.NewTelemetryConfig()}
+	c.Telemetry.Compression = "brotli"
+	c.Telemetry.Metrics.TLS.CertFile = "/etc/otel/metrics.pem"
+	err := c.Validate()
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "telemetry: invalid compression: brotli")
+	c.Telemetry.Compression = ""
+	err = c.Validate()
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "telemetry: metrics: tls: certFile and keyFile must be set together")
+}
+
+// TestTelemetry_Traceparent ensures a run joins the trace given by TRACEPARENT, following its
+// sampling decision
+func (ts *ValetTestSuite) TestTelemetry_Traceparent() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("replicas: 1\n"), 0644))
+	path := filepath.Join(tmp, "telemetry.jsonl")
+
+	ts.T().Setenv("VALET_TELEMETRY_ENABLED", "true")
+	ts.T().Setenv("VALET_TELEMETRY_EXPORTER", "file")
+	ts.T().Setenv("VALET_TELEMETRY_FILE_PATH", path)
+	ts.T().Setenv("VALET_TELEMETRY_METRICS_ENABLED", "false")
+	ts.T().Setenv("VALET_TELEMETRY_LOGS_ENABLED", "false")
+	// Root spans are never sampled; the sampled parent is followed
+	ts.T().Setenv("VALET_TELEMETRY_SAMPLE_RATE", "0")
+	ts.T().Setenv("TRACEPARENT", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ts.T().Setenv("TRACESTATE", "ci=job-42")
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", tmp})
+	ts.Require().NoError(rootCmd.Execute())
+
+	data, err := os.ReadFile(path)
+	ts.Require().NoError(err)
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					ParentSpanID string `json:"parentSpanId"`
+					TraceState   string `json:"traceState"`
+					Name         string
+				}
+			}
+		}
+	}
+	ts.Require().NoError(json.Unmarshal(data, &req))
+	found := false
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				ts.Equal("4bf92f3577b34da6a3ce929d0e0e4736", s.TraceID, "span %s must join the trace", s.Name)
+				if s.Name == "generate.command" {
+					found = true
+					ts.Equal("00f067aa0ba902b7", s.ParentSpanID)
+					ts.Equal("ci=job-42", s.TraceState)
+				}
+			}
+		}
+	}
+	ts.True(found, "no generate.command span exported")
+}
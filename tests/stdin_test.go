@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+// TestGenerate_Stdin ensures "generate -" reads values from stdin and writes the schema to stdout
+func (ts *ValetTestSuite) TestGenerate_Stdin() {
+	var out bytes.Buffer
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetIn(strings.NewReader("replicaCount: 2\nimage:\n  repository: nginx\n"))
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"generate", "-"})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+
+	var schema map[string]any
+	ts.Require().NoError(json.Unmarshal(out.Bytes(), &schema), "expected a JSON schema on stdout")
+	props := schema["properties"].(map[string]any)
+	ts.Equal("integer", props["replicaCount"].(map[string]any)["type"])
+	ts.Equal("object", props["image"].(map[string]any)["type"])
+}
+
+// TestGenerate_StdinJSON ensures JSON values on stdin are read and --format applies to stdout
+func (ts *ValetTestSuite) TestGenerate_StdinJSON() {
+	var out bytes.Buffer
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetIn(strings.NewReader(`{"zeta": "z", "alpha": 1}`))
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"generate", "--format", "yaml", "-"})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+
+	yaml := out.String()
+	ts.Contains(yaml, "# Code generated by valet. DO NOT EDIT.")
+	// Properties keep the input order
+	ts.Less(strings.Index(yaml, "zeta:"), strings.Index(yaml, "alpha:"))
+}
+
+// TestGenerate_StdinWatch ensures --watch is rejected when reading from stdin
+func (ts *ValetTestSuite) TestGenerate_StdinWatch() {
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetIn(strings.NewReader("a: 1\n"))
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetArgs([]string{"generate", "--watch", "-"})
+	err := rootCmd.Execute()
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "--watch cannot be used when reading values from stdin")
+}
+
+// TestGenerate_StdinDiscoveredOutput ensures the output of a discovered config file does not
+// redirect the schema of stdin values to a file, while --output does
+func (ts *ValetTestSuite) TestGenerate_StdinDiscoveredOutput() {
+	dir := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(dir, ".valet.yaml"), []byte("output: chart.schema.yaml\n"), 0644))
+	wd, err := os.Getwd()
+	ts.Require().NoError(err)
+	ts.Require().NoError(os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	var out bytes.Buffer
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetIn(strings.NewReader("b: 2\n"))
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"generate", "-"})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+
+	var schema map[string]any
+	ts.Require().NoError(json.Unmarshal(out.Bytes(), &schema), "expected a JSON schema on stdout")
+	ts.Contains(schema["properties"], "b")
+	ts.NoFileExists(filepath.Join(dir, "chart.schema.yaml"), "the chart's schema must not be replaced")
+
+	out.Reset()
+	rootCmd = cmd.NewRootCmd()
+	rootCmd.SetIn(strings.NewReader("b: 2\n"))
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"generate", "--output", "stream.schema.json", "-"})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+	ts.Empty(out.String())
+	ts.FileExists(filepath.Join(dir, "stream.schema.json"))
+}
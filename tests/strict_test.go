@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+// strictValues holds YAML 1.1 gotchas that change the loaded values
+const strictValues = `image:
+  tag: 1.10
+enabled: yes
+mode: 0755
+name: a
+name: b
+`
+
+// TestGenerate_StrictError ensures strict error mode rejects values with YAML 1.1 gotchas
+func (ts *ValetTestSuite) TestGenerate_StrictError() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte(strictValues), 0644))
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "--strict", "error", tmp})
+	err := rootCmd.Execute()
+	ts.Require().Error(err, "expected strict mode to fail")
+	ts.Contains(err.Error(), "strict mode: line 2, column 8: image.tag: 1.10 is parsed as the float 1.1")
+	ts.Contains(err.Error(), "(and 3 more)")
+	ts.NoFileExists(filepath.Join(tmp, "values.schema.json"))
+}
+
+// TestGenerate_StrictWarn ensures strict warn mode reports problems but still generates the schema
+func (ts *ValetTestSuite) TestGenerate_StrictWarn() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte(strictValues), 0644))
+	cfgFile := filepath.Join(tmp, "valet.yaml")
+	ts.Require().NoError(os.WriteFile(cfgFile, []byte("strict: warn\n"), 0644))
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "--config-file", cfgFile, tmp})
+	ts.Require().NoError(rootCmd.Execute(), "warn mode should not fail")
+
+	data, err := os.ReadFile(filepath.Join(tmp, "values.schema.json"))
+	ts.Require().NoError(err)
+	var schema map[string]any
+	ts.Require().NoError(json.Unmarshal(data, &schema))
+	props := schema["properties"].(map[string]any)
+	// yaml.v2 reads yes as a boolean, which is exactly what the warning is about
+	ts.Equal("boolean", props["enabled"].(map[string]any)["type"])
+}
+
+// TestGenerate_StrictInvalidMode ensures unknown strict modes are rejected
+func (ts *ValetTestSuite) TestGenerate_StrictInvalidMode() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("a: 1\n"), 0644))
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "--strict", "pedantic", tmp})
+	err := rootCmd.Execute()
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), `unsupported strict mode "pedantic"`)
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+// TestGenerate_TemplateReferences ensures values read by templates but not declared in
+// values.yaml are added to the schema as optional properties
+func (ts *ValetTestSuite) TestGenerate_TemplateReferences() {
+	tmp := ts.T().TempDir()
+	err := os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("image:\n  repository: nginx\nservers: []\n"), 0644)
+	ts.Require().NoError(err, "failed to write values.yaml")
+	ts.Require().NoError(os.Mkdir(filepath.Join(tmp, "templates"), 0755))
+	helpers := `{{- define "demo.port" -}}
+{{ .port | default 8080 }}
+{{- end }}
+`
+	deployment := `replicas: {{ .Values.replicaCount | default 1 }}
+image: "{{ .Values.image.repository }}:{{ default "latest" .Values.image.tag }}"
+debug: {{ .Values.debug | default false }}
+{{- with .Values.probe }}
+period: {{ .periodSeconds }}
+{{- end }}
+{{- range $server := .Values.servers }}
+- name: {{ $server.name }}
+  port: {{ include "demo.port" $server }}
+  release: {{ $.Release.Name }}
+{{- end }}
+`
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "templates", "_helpers.tpl"), []byte(helpers), 0644))
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "templates", "deployment.yaml"), []byte(deployment), 0644))
+
+	_, err = cmd.Generate(tmp, "")
+	ts.Require().NoError(err, "Generate failed")
+
+	data, err := os.ReadFile(filepath.Join(tmp, "values.schema.json"))
+	ts.Require().NoError(err)
+	var schema map[string]any
+	ts.Require().NoError(json.Unmarshal(data, &schema))
+	props := schema["properties"].(map[string]any)
+
+	ts.Equal(map[string]any{"type": "integer"}, props["replicaCount"])
+	ts.Equal(map[string]any{"type": "boolean"}, props["debug"])
+	image := props["image"].(map[string]any)["properties"].(map[string]any)
+	ts.Equal(map[string]any{"type": "string"}, image["tag"])
+	ts.Contains(image, "repository")
+	probe := props["probe"].(map[string]any)
+	ts.Equal("object", probe["type"])
+	ts.Contains(probe["properties"], "periodSeconds")
+	items := props["servers"].(map[string]any)["items"].(map[string]any)["properties"].(map[string]any)
+	ts.Contains(items, "name")
+	ts.Equal(map[string]any{"type": "integer"}, items["port"])
+	ts.NotContains(schema["required"], "replicaCount")
+}
+
+// TestGenerate_TemplateReferencesExampleChart scans the templates of the example chart
+func (ts *ValetTestSuite) TestGenerate_TemplateReferencesExampleChart() {
+	out := filepath.Join(ts.T().TempDir(), "values.schema.json")
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "-o", out, filepath.Join("..", "testdata", "mychart")})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+
+	data, err := os.ReadFile(out)
+	ts.Require().NoError(err)
+	var schema map[string]any
+	ts.Require().NoError(json.Unmarshal(data, &schema))
+	autoscaling := schema["properties"].(map[string]any)["autoscaling"].(map[string]any)
+	ts.Contains(autoscaling["properties"], "targetMemoryUtilizationPercentage")
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+// typegenValues exercises nested objects, arrays of objects and optional fields
+const typegenValues = `replicaCount: 2
+nameOverride: ""
+image:
+  repository: nginx
+  tag: ""
+ingress:
+  enabled: false
+  hosts:
+    - host: example.com
+      paths:
+        - path: /
+podAnnotations: {}
+"kubernetes.io/name": demo
+`
+
+// TestGenerate_TypeScript ensures TypeScript interfaces are generated from the schema
+func (ts *ValetTestSuite) TestGenerate_TypeScript() {
+	tmp := ts.T().TempDir()
+	err := os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte(typegenValues), 0644)
+	ts.Require().NoError(err, "failed to write values.yaml")
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "--format", "typescript", tmp})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+
+	data, err := os.ReadFile(filepath.Join(tmp, "values.ts"))
+	ts.Require().NoError(err, "expected values.ts to be written")
+	out := string(data)
+
+	ts.Contains(out, "// Code generated by valet. DO NOT EDIT.")
+	ts.Contains(out, "export interface Values {")
+	ts.Contains(out, "  replicaCount: number;")
+	ts.Contains(out, "  nameOverride?: string | null;")
+	ts.Contains(out, "  image: Image;")
+	ts.Contains(out, "  \"kubernetes.io/name\": string;")
+	ts.Contains(out, "  podAnnotations?: Record<string, unknown>;")
+	ts.Contains(out, "export interface Ingress {")
+	ts.Contains(out, "  hosts?: HostsItem[];")
+	ts.Contains(out, "export interface HostsItem {")
+	ts.Contains(out, "  paths: PathsItem[];")
+}
+
+// TestGenerate_GoTypes ensures Go structs with yaml and json tags are generated from the schema
+func (ts *ValetTestSuite) TestGenerate_GoTypes() {
+	tmp := ts.T().TempDir()
+	chart := filepath.Join(tmp, "mychart")
+	ts.Require().NoError(os.Mkdir(chart, 0755))
+	err := os.WriteFile(filepath.Join(chart, "values.yaml"), []byte(typegenValues), 0644)
+	ts.Require().NoError(err, "failed to write values.yaml")
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "--format", "go", "-o", "types.go", chart})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+
+	data, err := os.ReadFile(filepath.Join(chart, "types.go"))
+	ts.Require().NoError(err, "expected types.go to be written")
+	out := string(data)
+
+	ts.Contains(out, "// Code generated by valet. DO NOT EDIT.")
+	ts.Contains(out, "package mychart")
+	ts.Contains(out, "type Values struct {")
+	ts.Regexp("ReplicaCount +int64 +`yaml:\"replicaCount\" json:\"replicaCount\"`", out)
+	ts.Regexp("NameOverride +\\*string +`yaml:\"nameOverride,omitempty\" json:\"nameOverride,omitempty\"`", out)
+	ts.Regexp("Ingress +\\*Ingress +`yaml:\"ingress,omitempty\"", out)
+	ts.Regexp("KubernetesIoName +string +`yaml:\"kubernetes.io/name\"", out)
+	ts.Regexp("Hosts +\\[\\]HostsItem", out)
+	ts.Contains(out, "type PathsItem struct {")
+}
+
+// TestGenerate_UnknownFormat ensures unsupported formats are rejected
+func (ts *ValetTestSuite) TestGenerate_UnknownFormat() {
+	tmp := ts.T().TempDir()
+	err := os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("a: 1\n"), 0644)
+	ts.Require().NoError(err, "failed to write values.yaml")
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "--format", "xml", tmp})
+	err = rootCmd.Execute()
+	ts.Error(err)
+	ts.Contains(err.Error(), "unsupported output format")
+}
+
+// TestGenerate_FormatOutputMismatch ensures a format is not written to an output file with
+// the extension of another format
+func (ts *ValetTestSuite) TestGenerate_FormatOutputMismatch() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("a: 1\n"), 0644))
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, ".valet.yaml"), []byte("output: values.schema.json\n"), 0644))
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "--format", "go", tmp})
+	err := rootCmd.Execute()
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "values.schema.json does not match the go output format")
+	ts.NoFileExists(filepath.Join(tmp, "values.schema.json"))
+
+	// An output file of the format's own extension is written
+	rootCmd = cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "--format", "go", "-o", "values.go", tmp})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+	ts.FileExists(filepath.Join(tmp, "values.go"))
+}
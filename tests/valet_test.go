@@ -54,6 +54,13 @@ func (suite *ValetTestSuite) SetupSuite() {
 	// to avoid interfering with individual tests that need specific setups.
 }
 
+// SetupTest isolates every test from the user-level config files found by discovery
+func (suite *ValetTestSuite) SetupTest() {
+	home := suite.T().TempDir()
+	suite.T().Setenv("HOME", home)
+	suite.T().Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+}
+
 func TestValet(t *testing.T) {
 	suite.Run(t, new(ValetTestSuite))
 }
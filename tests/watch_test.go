@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestWatch_RegeneratesOnChange ensures the schema is regenerated and the change summarized
+func (ts *ValetTestSuite) TestWatch_RegeneratesOnChange() {
+	tmp := ts.T().TempDir()
+	valuesPath := filepath.Join(tmp, "values.yaml")
+	err := os.WriteFile(valuesPath, []byte("replicaCount: 1\nname: app\n"), 0644)
+	ts.Require().NoError(err, "failed to write values.yaml")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Watch(ctx, tmp, "", cmd.WatchOptions{Out: out})
+	}()
+
+	ts.Require().Eventually(func() bool {
+		return strings.Contains(out.String(), "Watching")
+	}, 5*time.Second, 20*time.Millisecond, "watcher did not start")
+
+	err = os.WriteFile(valuesPath, []byte("replicaCount: 3\nimage: nginx\n"), 0644)
+	ts.Require().NoError(err, "failed to update values.yaml")
+
+	ts.Require().Eventually(func() bool {
+		return strings.Contains(out.String(), "Regenerated")
+	}, 5*time.Second, 20*time.Millisecond, "schema was not regenerated")
+
+	output := out.String()
+	ts.Contains(output, "1 added, 1 removed, 1 changed")
+	ts.Contains(output, "+ image (string)")
+	ts.Contains(output, "- name")
+	ts.Contains(output, "~ replicaCount: default 1 -> 3")
+
+	cancel()
+	select {
+	case err := <-done:
+		ts.NoError(err, "Watch returned an error")
+	case <-time.After(5 * time.Second):
+		ts.Fail("Watch did not stop after cancel")
+	}
+}
+
+// TestWatch_ReloadsDiscoveredConfig ensures a discovered .valet.yaml is watched and reloaded
+func (ts *ValetTestSuite) TestWatch_ReloadsDiscoveredConfig() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("replicaCount: 1\n"), 0644))
+	configPath := filepath.Join(tmp, ".valet.yaml")
+	ts.Require().NoError(os.WriteFile(configPath, []byte("output: first.schema.json\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := &syncBuffer{}
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetOut(out)
+	rootCmd.SetArgs([]string{"generate", "--watch", tmp})
+	done := make(chan error, 1)
+	go func() {
+		done <- rootCmd.ExecuteContext(ctx)
+	}()
+
+	ts.Require().Eventually(func() bool {
+		return strings.Contains(out.String(), "Watching")
+	}, 5*time.Second, 20*time.Millisecond, "watcher did not start")
+	ts.FileExists(filepath.Join(tmp, "first.schema.json"))
+
+	ts.Require().NoError(os.WriteFile(configPath, []byte("output: second.schema.json\n"), 0644))
+	ts.Require().Eventually(func() bool {
+		_, err := os.Stat(filepath.Join(tmp, "second.schema.json"))
+		return err == nil
+	}, 5*time.Second, 20*time.Millisecond, "config change was not reloaded")
+
+	cancel()
+	select {
+	case err := <-done:
+		ts.NoError(err, "watch returned an error")
+	case <-time.After(5 * time.Second):
+		ts.Fail("watch did not stop after cancel")
+	}
+}
+
+// TestWatch_RegeneratesOnTemplateChange ensures a new .Values reference in a template regenerates the schema
+func (ts *ValetTestSuite) TestWatch_RegeneratesOnTemplateChange() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("replicaCount: 1\n"), 0644))
+	templates := filepath.Join(tmp, "templates")
+	ts.Require().NoError(os.Mkdir(templates, 0755))
+	deployment := filepath.Join(templates, "deployment.yaml")
+	ts.Require().NoError(os.WriteFile(deployment, []byte("replicas: {{ .Values.replicaCount }}\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Watch(ctx, tmp, "", cmd.WatchOptions{Out: out})
+	}()
+
+	ts.Require().Eventually(func() bool {
+		return strings.Contains(out.String(), "Watching")
+	}, 5*time.Second, 20*time.Millisecond, "watcher did not start")
+
+	err := os.WriteFile(deployment, []byte("replicas: {{ .Values.replicaCount }}\nimage: {{ .Values.image.tag }}\n"), 0644)
+	ts.Require().NoError(err, "failed to update the template")
+
+	ts.Require().Eventually(func() bool {
+		return strings.Contains(out.String(), "Regenerated")
+	}, 5*time.Second, 20*time.Millisecond, "schema was not regenerated")
+	ts.Contains(out.String(), "+ image ")
+
+	cancel()
+	select {
+	case err := <-done:
+		ts.NoError(err, "Watch returned an error")
+	case <-time.After(5 * time.Second):
+		ts.Fail("Watch did not stop after cancel")
+	}
+}
+
+// TestWatch_WatchesNewTemplateDirs ensures template directories created after the watch
+// started are watched too
+func (ts *ValetTestSuite) TestWatch_WatchesNewTemplateDirs() {
+	tmp := ts.T().TempDir()
+	ts.Require().NoError(os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte("replicaCount: 1\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Watch(ctx, tmp, "", cmd.WatchOptions{Out: out})
+	}()
+
+	ts.Require().Eventually(func() bool {
+		return strings.Contains(out.String(), "Watching")
+	}, 5*time.Second, 20*time.Millisecond, "watcher did not start")
+
+	sub := filepath.Join(tmp, "templates", "sub")
+	ts.Require().NoError(os.MkdirAll(sub, 0755))
+	ts.Require().Eventually(func() bool {
+		return strings.Contains(out.String(), "Regenerated")
+	}, 5*time.Second, 20*time.Millisecond, "creating templates/ did not regenerate the schema")
+
+	template := filepath.Join(sub, "deployment.yaml")
+	ts.Require().NoError(os.WriteFile(template, []byte("image: {{ .Values.image.tag }}\n"), 0644))
+	ts.Require().Eventually(func() bool {
+		return strings.Contains(out.String(), "+ image ")
+	}, 5*time.Second, 20*time.Millisecond, "template in a new directory was not picked up")
+
+	err := os.WriteFile(template, []byte("image: {{ .Values.image.tag }}\nport: {{ .Values.service.port }}\n"), 0644)
+	ts.Require().NoError(err, "failed to update the template")
+	ts.Require().Eventually(func() bool {
+		return strings.Contains(out.String(), "+ service ")
+	}, 5*time.Second, 20*time.Millisecond, "edit in a new template directory did not regenerate the schema")
+
+	cancel()
+	select {
+	case err := <-done:
+		ts.NoError(err, "Watch returned an error")
+	case <-time.After(5 * time.Second):
+		ts.Fail("Watch did not stop after cancel")
+	}
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mkm29/valet/cmd"
+)
+
+// yamlOutValues has keys out of alphabetical order and comments to carry over
+const yamlOutValues = `# Number of replicas
+replicaCount: 1
+image:
+  # Image repository
+  repository: nginx
+  pullPolicy: IfNotPresent
+affinity: {}
+`
+
+// TestGenerate_YAMLOutput ensures the schema is written as YAML in values order with comments
+func (ts *ValetTestSuite) TestGenerate_YAMLOutput() {
+	tmp := ts.T().TempDir()
+	err := os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte(yamlOutValues), 0644)
+	ts.Require().NoError(err, "failed to write values.yaml")
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "--format", "yaml", tmp})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+
+	data, err := os.ReadFile(filepath.Join(tmp, "values.schema.yaml"))
+	ts.Require().NoError(err, "expected values.schema.yaml to be written")
+	out := string(data)
+
+	ts.True(strings.HasPrefix(out, "# Code generated by valet. DO NOT EDIT.\n"), "missing header: %s", out)
+	ts.Contains(out, "properties:\n  # Number of replicas\n  replicaCount:\n    type: integer\n")
+	ts.Contains(out, "      # Image repository\n      repository:\n")
+	replicas := strings.Index(out, "  replicaCount:")
+	image := strings.Index(out, "  image:")
+	affinity := strings.Index(out, "  affinity:")
+	ts.True(replicas < image && image < affinity, "expected values.yaml key order, got %s", out)
+	ts.Contains(out, "required:\n  - replicaCount\n  - image\n")
+}
+
+// TestGenerate_FormatFromOutput ensures the output format is inferred from the output extension
+func (ts *ValetTestSuite) TestGenerate_FormatFromOutput() {
+	tmp := ts.T().TempDir()
+	err := os.WriteFile(filepath.Join(tmp, "values.yaml"), []byte(yamlOutValues), 0644)
+	ts.Require().NoError(err, "failed to write values.yaml")
+
+	rootCmd := cmd.NewRootCmd()
+	rootCmd.SetArgs([]string{"generate", "-o", "schema.yml", tmp})
+	ts.Require().NoError(rootCmd.Execute(), "Execute failed")
+
+	data, err := os.ReadFile(filepath.Join(tmp, "schema.yml"))
+	ts.Require().NoError(err, "expected schema.yml to be written")
+	ts.Contains(string(data), "$schema: http://json-schema.org/schema#")
+	ts.NoFileExists(filepath.Join(tmp, "values.schema.json"))
+}